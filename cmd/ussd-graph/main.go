@@ -0,0 +1,136 @@
+// Command ussd-graph renders the navigation in config.yaml as a Graphviz or
+// Mermaid diagram.
+//
+//	ussd-graph -config config.yaml -format mermaid -metrics http://localhost:8080/metrics
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/jamesdube/ussd/pkg/router"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const trafficMetric = "ussd_route_requests_total"
+
+type config struct {
+	Menu struct {
+		Navigation map[string]string
+	}
+}
+
+func main() {
+
+	file := flag.String("config", "config.yaml", "config file holding menu.navigation")
+	format := flag.String("format", "dot", "output format, dot or mermaid")
+	metrics := flag.String("metrics", "", "metrics endpoint or file to overlay traffic from")
+	flag.Parse()
+
+	err := run(*file, *format, *metrics, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(file string, format string, metrics string, w io.Writer) error {
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var c config
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	keys := make([]string, 0, len(c.Menu.Navigation))
+	for k := range c.Menu.Navigation {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	r := router.NewRouter()
+	for _, k := range keys {
+		err = r.AddRoute(k, c.Menu.Navigation[k], nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	var traffic map[string]float64
+	if metrics != "" {
+		traffic, err = readTraffic(metrics)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case "dot":
+		return r.Dot(w, traffic)
+	case "mermaid":
+		return r.Mermaid(w, traffic)
+	}
+
+	return fmt.Errorf("unknown format %s", format)
+}
+
+// readTraffic reads the route request counters from a Prometheus text
+// exposition, fetched over http or read from a file.
+func readTraffic(src string) (map[string]float64, error) {
+
+	var rd io.Reader
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		res, err := http.Get(src)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", src, res.Status)
+		}
+		rd = res.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		rd = f
+	}
+
+	traffic := map[string]float64{}
+	prefix := trafficMetric + `{route="`
+
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+
+		rest := line[len(prefix):]
+		end := strings.Index(rest, `"}`)
+		if end < 0 {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(strings.TrimSpace(rest[end+2:]), 64)
+		if err != nil {
+			continue
+		}
+		traffic[rest[:end]] += v
+	}
+
+	return traffic, sc.Err()
+}
@@ -74,6 +74,11 @@ func (e *EconetGateway) Request() Request {
 	return Request{}
 }
 
+// Limit is the length of a USSD string in the GSM 7 bit alphabet.
+func (e *EconetGateway) Limit() int {
+	return 182
+}
+
 func (e *EconetGateway) Name() string {
 	return "econet"
 }
@@ -0,0 +1,7 @@
+package gateway
+
+// Limiter is implemented by gateways whose network caps the number of
+// characters on a screen, longer responses are split in pages.
+type Limiter interface {
+	Limit() int
+}
@@ -0,0 +1,32 @@
+package i18n
+
+// Builtin holds the English messages used by the framework, catalogs can
+// override any of them.
+var Builtin = map[string]interface{}{
+	"menu.more":              "More",
+	"menu.previous":          "Previous",
+	"menu.no_more_options":   "No more options",
+	"menu.invalid_selection": "Invalid menu option",
+	"menu.select_option":     "Please select an option:",
+
+	"input.number":       "Please enter a number",
+	"input.amount":       "Please enter a valid amount",
+	"input.amount_min":   "Amount must be at least %.2f",
+	"input.amount_range": "Amount must be between %.2f and %.2f",
+	"input.date":         "Please enter a date as %s",
+	"input.pin":          "PIN must be %d digits",
+	"input.national_id":  "Please enter a valid national ID",
+	"input.msisdn":       "Please enter a valid phone number",
+	"input.exhausted":    "Too many invalid attempts, please try again later",
+
+	"form.title":     "Please confirm",
+	"form.confirm":   "Confirm",
+	"form.edit":      "Edit",
+	"form.cancel":    "Cancel",
+	"form.select":    "Select the field to change",
+	"form.done":      "Thank you, your request has been submitted",
+	"form.cancelled": "Request cancelled",
+	"form.failed":    "Your request could not be completed, please try again later",
+
+	"proxy.error": "Service unavailable, please try again later",
+}
@@ -0,0 +1,258 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Message is a translated text, with plural forms keyed by category (zero,
+// one, two, few, many, other) when it depends on a count.
+type Message struct {
+	Text   string
+	Plural map[string]string
+}
+
+// PluralRule returns the plural category of n.
+type PluralRule func(n int) string
+
+// Bundle holds the message catalogs of every locale. Lookups try the
+// locale, its language without region, the fallback locale and finally the
+// built-in English messages, returning the key when all of them miss.
+type Bundle struct {
+	catalogs map[string]map[string]Message
+	rules    map[string]PluralRule
+	fallback string
+	mu       sync.RWMutex
+}
+
+func NewBundle(fallback string) *Bundle {
+	return &Bundle{
+		catalogs: map[string]map[string]Message{},
+		rules:    map[string]PluralRule{},
+		fallback: fallback,
+	}
+}
+
+var builtin = func() *Bundle {
+	b := NewBundle("en")
+	b.Add("en", Builtin)
+	return b
+}()
+
+// Default returns the bundle holding only the built-in messages.
+func Default() *Bundle {
+	return builtin
+}
+
+// Add merges messages into the catalog of locale. Values are strings, maps
+// of plural categories or nested maps whose keys are joined with dots.
+func (b *Bundle) Add(locale string, messages map[string]interface{}) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.catalogs[locale]
+	if c == nil {
+		c = map[string]Message{}
+		b.catalogs[locale] = c
+	}
+	flatten(c, "", messages)
+}
+
+// Load reads a YAML or JSON catalog named after its locale, such as
+// locales/sn.yaml.
+func (b *Bundle) Load(path string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	messages := map[string]interface{}{}
+	ext := filepath.Ext(path)
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(data, &messages)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &messages)
+	default:
+		return fmt.Errorf("%s: unsupported catalog format", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	b.Add(strings.TrimSuffix(filepath.Base(path), ext), messages)
+	return nil
+}
+
+// LoadDir loads every catalog in dir.
+func (b *Bundle) LoadDir(dir string) error {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		switch filepath.Ext(e.Name()) {
+		case ".json", ".yaml", ".yml":
+			err = b.Load(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetPluralRule replaces the plural rule of a language, which by default
+// picks "one" for 1 and "other" for everything else, "zero" for 0 when the
+// message has that form.
+func (b *Bundle) SetPluralRule(locale string, r PluralRule) {
+	b.mu.Lock()
+	b.rules[locale] = r
+	b.mu.Unlock()
+}
+
+// T returns the message for key in locale formatted with args.
+func (b *Bundle) T(locale string, key string, args ...interface{}) string {
+
+	m, _, ok := b.find(locale, key)
+	if !ok {
+		return key
+	}
+	return format(m.Text, args)
+}
+
+// Plural returns the form of the message for key matching the count n,
+// formatted with args.
+func (b *Bundle) Plural(locale string, key string, n int, args ...interface{}) string {
+
+	m, loc, ok := b.find(locale, key)
+	if !ok {
+		return key
+	}
+	if m.Plural == nil {
+		return format(m.Text, args)
+	}
+
+	if n == 0 {
+		if t, ok := m.Plural["zero"]; ok {
+			return format(t, args)
+		}
+	}
+
+	t, ok := m.Plural[b.rule(loc)(n)]
+	if !ok {
+		t = m.Plural["other"]
+	}
+	return format(t, args)
+}
+
+// find looks the key up in the candidate locales, returning the locale it
+// was found in.
+func (b *Bundle) find(locale string, key string) (Message, string, bool) {
+
+	b.mu.RLock()
+	for _, l := range candidates(locale, b.fallback) {
+		if m, ok := b.catalogs[l][key]; ok {
+			b.mu.RUnlock()
+			return m, l, true
+		}
+	}
+	b.mu.RUnlock()
+
+	if b != builtin {
+		return builtin.find("en", key)
+	}
+	return Message{}, "", false
+}
+
+func (b *Bundle) rule(locale string) PluralRule {
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if r, ok := b.rules[locale]; ok {
+		return r
+	}
+	if r, ok := b.rules[language(locale)]; ok {
+		return r
+	}
+	return func(n int) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
+
+func candidates(locale string, fallback string) []string {
+	var ls []string
+	for _, l := range []string{locale, language(locale), fallback, language(fallback)} {
+		if l != "" && (len(ls) == 0 || ls[len(ls)-1] != l) {
+			ls = append(ls, l)
+		}
+	}
+	return ls
+}
+
+// language strips the region of a locale such as en-US or en_US.
+func language(locale string) string {
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		return locale[:i]
+	}
+	return locale
+}
+
+var categories = map[string]bool{"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true}
+
+func flatten(c map[string]Message, prefix string, messages map[string]interface{}) {
+
+	for k, v := range messages {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case string:
+			c[key] = Message{Text: v}
+		case map[string]interface{}:
+			if plural, ok := pluralForms(v); ok {
+				c[key] = Message{Text: plural["other"], Plural: plural}
+				continue
+			}
+			flatten(c, key, v)
+		default:
+			c[key] = Message{Text: fmt.Sprint(v)}
+		}
+	}
+}
+
+func pluralForms(v map[string]interface{}) (map[string]string, bool) {
+	forms := map[string]string{}
+	for k, f := range v {
+		s, ok := f.(string)
+		if !categories[k] || !ok {
+			return nil, false
+		}
+		forms[k] = s
+	}
+	return forms, len(forms) > 0
+}
+
+// format applies args to t, messages without verbs such as a zero form are
+// returned as they are.
+func format(t string, args []interface{}) string {
+	if len(args) == 0 || !strings.Contains(t, "%") {
+		return t
+	}
+	return fmt.Sprintf(t, args...)
+}
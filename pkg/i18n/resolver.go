@@ -0,0 +1,25 @@
+package i18n
+
+// Resolver returns the locale of a subscriber from the MSISDN or the session
+// attributes, or an empty string when it does not know it.
+type Resolver func(msisdn string, attributes map[string]string) string
+
+// Attribute resolves the locale stored in a session attribute, such as the
+// one set by a language selection menu.
+func Attribute(name string) Resolver {
+	return func(msisdn string, attributes map[string]string) string {
+		return attributes[name]
+	}
+}
+
+// Chain returns the locale of the first resolver that knows it.
+func Chain(resolvers ...Resolver) Resolver {
+	return func(msisdn string, attributes map[string]string) string {
+		for _, r := range resolvers {
+			if l := r(msisdn, attributes); l != "" {
+				return l
+			}
+		}
+		return ""
+	}
+}
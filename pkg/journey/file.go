@@ -0,0 +1,95 @@
+package journey
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// File appends hops to a JSON lines file.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Record(h Hop) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	fl, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer fl.Close()
+
+	_, err = fl.Write(append(b, '\n'))
+	return err
+}
+
+func (f *File) Erase(subscriber string) error {
+	return f.rewrite(func(h Hop) bool {
+		return h.Subscriber != subscriber
+	})
+}
+
+func (f *File) Purge(before time.Time) error {
+	return f.rewrite(func(h Hop) bool {
+		return !h.Time.Before(before)
+	})
+}
+
+// rewrite copies the hops to keep into a temporary file which then replaces
+// the trail.
+func (f *File) rewrite(keep func(h Hop) bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	in, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := f.path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var h Hop
+		if json.Unmarshal(sc.Bytes(), &h) == nil && !keep(h) {
+			continue
+		}
+		if _, err = out.Write(append(sc.Bytes(), '\n')); err != nil {
+			out.Close()
+			return err
+		}
+	}
+
+	if err = sc.Err(); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.path)
+}
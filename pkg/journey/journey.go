@@ -0,0 +1,167 @@
+package journey
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/jamesdube/ussd/internal/utils"
+	"sync"
+	"time"
+)
+
+// Hop is a single request/response exchange of a session as seen by the
+// subscriber.
+type Hop struct {
+	SessionId  string        `json:"sessionId"`
+	Subscriber string        `json:"subscriber"`
+	Msisdn     string        `json:"msisdn"`
+	Time       time.Time     `json:"time"`
+	Message    string        `json:"message"`
+	Route      string        `json:"route"`
+	InputRoute string        `json:"inputRoute"`
+	Menu       string        `json:"menu"`
+	Prompt     string        `json:"prompt"`
+	Navigation string        `json:"navigation"`
+	Active     bool          `json:"active"`
+	Latency    time.Duration `json:"latency"`
+	// Secret is set when the message is the input of a secret menu, such
+	// as a PIN, the recorder always redacts it.
+	Secret bool `json:"-"`
+}
+
+// Sink stores hops. Subscribers are identified by Hop.Subscriber so records
+// can be erased even when the msisdn itself has been redacted.
+type Sink interface {
+	Record(h Hop) error
+	Erase(subscriber string) error
+	Purge(before time.Time) error
+}
+
+// queueSize is the number of hops buffered for the sink, hops recorded while
+// the queue is full are dropped.
+const queueSize = 1024
+
+// Recorder writes hops to the sink from a background goroutine so a slow sink
+// never delays a response.
+type Recorder struct {
+	sink      Sink
+	secret    []byte
+	redactors []Redactor
+	queue     chan op
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// op is a hop to record or, with done set, a subscriber to erase.
+type op struct {
+	hop   Hop
+	erase string
+	done  chan error
+}
+
+// NewRecorder starts a recorder writing to sink. Subscribers are identified
+// by an HMAC of their msisdn keyed with secret, which must be kept private
+// and stable for Erase to find earlier hops.
+func NewRecorder(sink Sink, secret []byte, redactors ...Redactor) *Recorder {
+
+	if len(secret) == 0 {
+		panic(errors.New("journey recorder requires a subscriber secret"))
+	}
+
+	r := &Recorder{
+		sink:      sink,
+		secret:    secret,
+		redactors: redactors,
+		queue:     make(chan op, queueSize),
+		stopped:   make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *Recorder) run() {
+
+	defer close(r.stopped)
+
+	for o := range r.queue {
+		if o.done != nil {
+			o.done <- r.sink.Erase(o.erase)
+			continue
+		}
+
+		err := r.sink.Record(o.hop)
+		if err != nil {
+			utils.Logger.Error("failed to record journey hop", "sessionId", o.hop.SessionId, "error", err)
+		}
+	}
+}
+
+// Record redacts the hop and queues it for the sink. Failures are logged, the
+// trail must never break a session.
+func (r *Recorder) Record(h Hop) {
+
+	h.Subscriber = Subscriber(r.secret, h.Msisdn)
+	if h.Secret {
+		h.Message = redacted
+	}
+	for _, rd := range r.redactors {
+		rd(&h)
+	}
+
+	select {
+	case r.queue <- op{hop: h}:
+	default:
+		utils.Logger.Warn("journey queue is full, dropping hop", "sessionId", h.SessionId)
+	}
+}
+
+// Erase removes every hop recorded for the msisdn, including hops still
+// queued.
+func (r *Recorder) Erase(msisdn string) error {
+
+	s := Subscriber(r.secret, msisdn)
+	utils.Logger.Info("erasing journey", "subscriber", s)
+
+	done := make(chan error, 1)
+	r.queue <- op{erase: s, done: done}
+	return <-done
+}
+
+// Close writes the queued hops and stops the recorder, it must not be used
+// afterwards.
+func (r *Recorder) Close() {
+	r.closeOnce.Do(func() {
+		close(r.queue)
+	})
+	<-r.stopped
+}
+
+// Retain purges hops older than d every interval until stop is closed.
+func (r *Recorder) Retain(d time.Duration, interval time.Duration, stop <-chan struct{}) {
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-t.C:
+				err := r.sink.Purge(now.Add(-d))
+				if err != nil {
+					utils.Logger.Error("failed to purge journey", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Subscriber returns the stable identifier under which hops of an msisdn are
+// stored, an HMAC so the msisdn can not be recovered without the secret.
+func Subscriber(secret []byte, msisdn string) string {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(msisdn))
+	return hex.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,63 @@
+package journey
+
+import (
+	"sync"
+	"time"
+)
+
+// InMemory keeps hops in memory, it is meant for tests.
+type InMemory struct {
+	hops []Hop
+	mu   sync.RWMutex
+}
+
+func NewInMemory() *InMemory {
+	return &InMemory{}
+}
+
+func (im *InMemory) Record(h Hop) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	im.hops = append(im.hops, h)
+	return nil
+}
+
+func (im *InMemory) Erase(subscriber string) error {
+	return im.filter(func(h Hop) bool {
+		return h.Subscriber != subscriber
+	})
+}
+
+func (im *InMemory) Purge(before time.Time) error {
+	return im.filter(func(h Hop) bool {
+		return !h.Time.Before(before)
+	})
+}
+
+// Hops returns the hops recorded for a session in the order they happened.
+func (im *InMemory) Hops(sessionId string) []Hop {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	var hops []Hop
+	for _, h := range im.hops {
+		if h.SessionId == sessionId {
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+func (im *InMemory) filter(keep func(h Hop) bool) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	hops := im.hops[:0]
+	for _, h := range im.hops {
+		if keep(h) {
+			hops = append(hops, h)
+		}
+	}
+	im.hops = hops
+	return nil
+}
@@ -0,0 +1,43 @@
+package journey
+
+import (
+	"regexp"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+// Redactor strips personal data from a hop before it is stored.
+type Redactor func(h *Hop)
+
+// MaskMsisdn keeps the last visible digits of the msisdn.
+func MaskMsisdn(visible int) Redactor {
+	return func(h *Hop) {
+		if len(h.Msisdn) <= visible {
+			return
+		}
+		n := len(h.Msisdn) - visible
+		h.Msisdn = strings.Repeat("*", n) + h.Msisdn[n:]
+	}
+}
+
+// RedactPattern replaces matches of re in the inbound message and the prompt.
+func RedactPattern(re *regexp.Regexp, replacement string) Redactor {
+	return func(h *Hop) {
+		h.Message = re.ReplaceAllString(h.Message, replacement)
+		h.Prompt = re.ReplaceAllString(h.Prompt, replacement)
+	}
+}
+
+// RedactInput hides the inbound message sent to the menus of the given
+// routes, such as PIN entry screens.
+func RedactInput(routes ...string) Redactor {
+	return func(h *Hop) {
+		for _, r := range routes {
+			if h.InputRoute == r {
+				h.Message = redacted
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package journey
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schema is the table expected by SQL, with %s replaced by the table name.
+const Schema = `CREATE TABLE %s (
+	session_id  VARCHAR(128) NOT NULL,
+	subscriber  CHAR(64)     NOT NULL,
+	msisdn      VARCHAR(32)  NOT NULL,
+	time        TIMESTAMP    NOT NULL,
+	message     TEXT,
+	route       VARCHAR(255),
+	input_route VARCHAR(255),
+	menu        VARCHAR(255),
+	prompt      TEXT,
+	navigation  VARCHAR(32),
+	active      BOOLEAN,
+	latency_ms  BIGINT
+)`
+
+// SQL stores hops in a database table. The driver is left to the caller,
+// placeholder renders the n-th (1-based) bind parameter, "?" when nil.
+type SQL struct {
+	db          *sql.DB
+	table       string
+	placeholder func(n int) string
+}
+
+func NewSQL(db *sql.DB, table string, placeholder func(n int) string) *SQL {
+	if placeholder == nil {
+		placeholder = func(int) string { return "?" }
+	}
+	return &SQL{
+		db:          db,
+		table:       table,
+		placeholder: placeholder,
+	}
+}
+
+func (s *SQL) Record(h Hop) error {
+
+	columns := []string{"session_id", "subscriber", "msisdn", "time", "message", "route", "input_route", "menu", "prompt", "navigation", "active", "latency_ms"}
+	params := make([]string, len(columns))
+	for i := range params {
+		params[i] = s.placeholder(i + 1)
+	}
+
+	q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", s.table, strings.Join(columns, ", "), strings.Join(params, ", "))
+	_, err := s.db.Exec(q, h.SessionId, h.Subscriber, h.Msisdn, h.Time, h.Message, h.Route, h.InputRoute, h.Menu, h.Prompt, h.Navigation, h.Active, h.Latency.Milliseconds())
+	return err
+}
+
+func (s *SQL) Erase(subscriber string) error {
+	_, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE subscriber = %s", s.table, s.placeholder(1)), subscriber)
+	return err
+}
+
+func (s *SQL) Purge(before time.Time) error {
+	_, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE time < %s", s.table, s.placeholder(1)), before)
+	return err
+}
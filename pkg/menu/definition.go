@@ -0,0 +1,156 @@
+package menu
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Definition is a menu described in configuration instead of Go code. It
+// shows a prompt with numbered options, optionally stores the input in a
+// session attribute and moves to the menu named by the chosen option or by
+// Goto, falling back to the route of the selection. End makes it a final
+// screen. Once compiled, the prompt and option labels are templates.
+type Definition struct {
+	Prompt  string
+	Options []OptionDefinition
+	PerPage int `yaml:"perPage"`
+	// Capture names the session attribute the input is stored in, for menus
+	// with options the value of the chosen option.
+	Capture string
+	Goto    string
+	End     bool
+
+	prompt  *Template
+	options []*Template
+}
+
+// OptionDefinition is an option of a Definition, Value defaults to Label.
+type OptionDefinition struct {
+	Label string
+	Value string
+	Goto  string
+}
+
+// Compile parses the prompt and option labels as templates, definitions
+// that are not compiled show them as they are.
+func (d *Definition) Compile() error {
+
+	p, err := NewTemplate(d.Prompt)
+	if err != nil {
+		return fmt.Errorf("prompt: %w", err)
+	}
+
+	options := make([]*Template, len(d.Options))
+	for i, o := range d.Options {
+		options[i], err = NewTemplate(o.Label)
+		if err != nil {
+			return fmt.Errorf("option %d: %w", i+1, err)
+		}
+	}
+
+	d.prompt = p
+	d.options = options
+	return nil
+}
+
+func (d *Definition) OnRequest(c *Context, msg string) Response {
+
+	r := Response{Prompt: d.Prompt}
+	if d.prompt != nil {
+		r.Prompt = d.prompt.Render(c)
+	}
+	for i, o := range d.Options {
+		if d.options != nil {
+			r.Options = append(r.Options, d.options[i].Render(c))
+			continue
+		}
+		r.Options = append(r.Options, o.Label)
+	}
+
+	if d.PerPage > 0 && len(d.Options) > d.PerPage {
+		r.Paginated = true
+		r.PerPage = d.PerPage
+	}
+
+	if d.End {
+		r.NavigationType = Stop
+	}
+	return r
+}
+
+// Process captures the input and sets the transition, input that is not one
+// of the options shows the menu again.
+func (d *Definition) Process(c *Context, msg string) NavigationType {
+
+	next, value := d.Goto, msg
+
+	if len(d.Options) > 0 {
+		i, err := strconv.Atoi(c.Selected)
+		if err != nil || i < 1 || i > len(d.Options) {
+			c.NavigationType = Replay
+			return Replay
+		}
+
+		o := d.Options[i-1]
+		value = o.Label
+		if o.Value != "" {
+			value = o.Value
+		}
+		if o.Goto != "" {
+			next = o.Goto
+		}
+	}
+
+	if d.Capture != "" && c.Context != nil {
+		c.Add(d.Capture, value)
+	}
+
+	if next != "" {
+		c.Goto(next)
+	}
+
+	return Continue
+}
+
+// Targets returns the names of the menus the definition can move to.
+func (d *Definition) Targets() []string {
+	var targets []string
+	if d.Goto != "" {
+		targets = append(targets, d.Goto)
+	}
+	for _, o := range d.Options {
+		if o.Goto != "" {
+			targets = append(targets, o.Goto)
+		}
+	}
+	return targets
+}
+
+// LoadDefinitions reads menu definitions keyed by menu name from a YAML or
+// JSON file, depending on its extension.
+func LoadDefinitions(path string) (map[string]*Definition, error) {
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := map[string]*Definition{}
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(b, &defs)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &defs)
+	default:
+		return nil, fmt.Errorf("%s: unsupported menu definition format", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return defs, nil
+}
@@ -0,0 +1,202 @@
+package menu
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	formSummary   = "summary"
+	formEdit      = "edit"
+	formDone      = "done"
+	formCancelled = "cancelled"
+	formFailed    = "failed"
+)
+
+// SubmitFunc receives the values captured by a form keyed by attribute.
+type SubmitFunc func(c *Context, values map[string]string) error
+
+// Form is a menu asking for its fields one after the other on a single
+// route, then showing a summary to confirm, edit a field or cancel. Submit is
+// called once per session when the summary is confirmed, after which the
+// session ends with Done or moves to Next. Texts left empty use the form.*
+// messages of the subscriber's locale. The progress is kept in session
+// attributes prefixed with the form name.
+type Form struct {
+	Name      string
+	Title     string
+	Fields    []*Input
+	Submit    SubmitFunc
+	Next      string
+	Done      string
+	Cancelled string
+	Failed    string
+}
+
+func NewForm(name string, submit SubmitFunc, fields ...*Input) *Form {
+	return &Form{
+		Name:   name,
+		Fields: fields,
+		Submit: submit,
+	}
+}
+
+func (f *Form) OnRequest(c *Context, msg string) Response {
+
+	if !c.IsReplay() {
+		f.reset(c)
+	}
+
+	switch step := c.Get(f.key("step")); step {
+	case formSummary:
+		return Response{Prompt: f.summary(c), Options: []string{c.T("form.confirm"), c.T("form.edit"), c.T("form.cancel")}}
+	case formEdit:
+		var labels []string
+		for _, fd := range f.Fields {
+			labels = append(labels, fd.label())
+		}
+		return Response{Prompt: c.T("form.select"), Options: labels}
+	case formDone:
+		return Response{Prompt: text(c, f.Done, "form.done"), NavigationType: Stop}
+	case formCancelled:
+		return Response{Prompt: text(c, f.Cancelled, "form.cancelled"), NavigationType: Stop}
+	case formFailed:
+		return Response{Prompt: text(c, f.Failed, "form.failed"), NavigationType: Stop}
+	default:
+		fd := f.Fields[f.index(c)]
+		if c.Get(fd.key("error")) == "" {
+			return Response{Prompt: fd.Prompt}
+		}
+		return fd.OnRequest(c, msg)
+	}
+}
+
+func (f *Form) Process(c *Context, msg string) NavigationType {
+
+	c.NavigationType = Replay
+
+	switch c.Get(f.key("step")) {
+	case formSummary:
+		switch msg {
+		case "1":
+			return f.confirm(c)
+		case "2":
+			c.Add(f.key("step"), formEdit)
+		case "3":
+			c.Add(f.key("step"), formCancelled)
+		}
+	case formDone, formCancelled, formFailed:
+		return Replay
+	case formEdit:
+		i, err := strconv.Atoi(msg)
+		if err == nil && i >= 1 && i <= len(f.Fields) {
+			c.Add(f.key("step"), strconv.Itoa(i-1))
+			c.Add(f.key("editing"), "true")
+		}
+	default:
+		i := f.index(c)
+		if f.Fields[i].Process(c, msg) != Continue {
+			return Replay
+		}
+		// the form decides what follows, not the Next of the field
+		c.Target = ""
+
+		if c.Get(f.key("editing")) != "" || i == len(f.Fields)-1 {
+			delete(c.Context, f.key("editing"))
+			c.Add(f.key("step"), formSummary)
+		} else {
+			c.Add(f.key("step"), strconv.Itoa(i+1))
+		}
+	}
+
+	return Replay
+}
+
+// confirm submits the values unless the session already did.
+func (f *Form) confirm(c *Context) NavigationType {
+
+	if c.Get(f.key("submitted")) == "" {
+		c.Add(f.key("submitted"), "true")
+
+		values := map[string]string{}
+		for _, fd := range f.Fields {
+			values[fd.Attribute] = c.Get(fd.Attribute)
+		}
+
+		if f.Submit != nil {
+			err := f.Submit(c, values)
+			if err != nil {
+				c.Add(f.key("step"), formFailed)
+				return Replay
+			}
+		}
+	}
+
+	if f.Next != "" {
+		c.NavigationType = Continue
+		c.Goto(f.Next)
+		return Continue
+	}
+
+	c.Add(f.key("step"), formDone)
+	return Replay
+}
+
+// DisableGlobalNavigation keeps forms with secret fields from taking their
+// input for the back and home keys.
+func (f *Form) DisableGlobalNavigation() bool {
+	return f.SecretInput()
+}
+
+// SecretInput reports whether the form has secret fields.
+func (f *Form) SecretInput() bool {
+	for _, fd := range f.Fields {
+		if fd.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Form) summary(c *Context) string {
+
+	var sb strings.Builder
+	sb.WriteString(text(c, f.Title, "form.title"))
+	for _, fd := range f.Fields {
+		v := c.Get(fd.Attribute)
+		if fd.Secret {
+			v = strings.Repeat("*", len(v))
+		}
+		sb.WriteString("\n" + fd.label() + ": " + v)
+	}
+	return sb.String()
+}
+
+func (f *Form) index(c *Context) int {
+	i, _ := strconv.Atoi(c.Get(f.key("step")))
+	if i < 0 || i >= len(f.Fields) {
+		return 0
+	}
+	return i
+}
+
+func (f *Form) key(k string) string {
+	return f.Name + "." + k
+}
+
+func (f *Form) reset(c *Context) {
+	c.Add(f.key("step"), "0")
+	delete(c.Context, f.key("editing"))
+	delete(c.Context, f.key("submitted"))
+	for _, fd := range f.Fields {
+		fd.reset(c)
+	}
+}
+
+// text returns s, or the message for key when s is empty.
+func text(c *Context, s string, key string) string {
+	if s != "" {
+		return s
+	}
+	return c.T(key)
+}
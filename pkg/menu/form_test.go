@@ -0,0 +1,145 @@
+package menu
+
+import (
+	"errors"
+	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/session"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	utils.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+// drive shows the form, then sends every input the way the processor does:
+// Process on a fresh context, then OnRequest again when it replays.
+func drive(f *Form, inputs ...string) (Response, *Context) {
+
+	ss := session.NewSession("s1")
+	c := NewContext("263771234567", ss)
+	r := f.OnRequest(c, "")
+
+	for _, msg := range inputs {
+		c = NewContext("263771234567", ss)
+		f.Process(c, msg)
+		if c.NavigationType == Replay && c.Target == "" {
+			r = f.OnRequest(c, msg)
+		}
+	}
+	return r, c
+}
+
+func TestForm(t *testing.T) {
+
+	failing := errors.New("core banking down")
+
+	tests := []struct {
+		name       string
+		next       string
+		submitErr  error
+		inputs     []string
+		wantPrompt string
+		wantTarget string
+		wantCalls  int
+		wantValues map[string]string
+	}{
+		{
+			name:       "fill and confirm",
+			inputs:     []string{"1001", "20", "1"},
+			wantPrompt: "Thank you, your request has been submitted",
+			wantCalls:  1,
+			wantValues: map[string]string{"account": "1001", "amount": "20.00"},
+		},
+		{
+			name:       "invalid input asks again",
+			inputs:     []string{"1001", "abc", "20", "1"},
+			wantPrompt: "Thank you, your request has been submitted",
+			wantCalls:  1,
+			wantValues: map[string]string{"account": "1001", "amount": "20.00"},
+		},
+		{
+			name:       "edit then confirm twice",
+			inputs:     []string{"1001", "20", "2", "1", "2002", "1", "1"},
+			wantPrompt: "Thank you, your request has been submitted",
+			wantCalls:  1,
+			wantValues: map[string]string{"account": "2002", "amount": "20.00"},
+		},
+		{
+			name:       "edit goes back to the summary",
+			inputs:     []string{"1001", "20", "2", "2", "35"},
+			wantPrompt: "Please confirm\naccount: 1001\namount: 35.00",
+		},
+		{
+			name:       "confirm twice with next",
+			next:       "receipt",
+			inputs:     []string{"1001", "20", "1", "1"},
+			wantTarget: "receipt",
+			wantCalls:  1,
+			wantValues: map[string]string{"account": "1001", "amount": "20.00"},
+		},
+		{
+			name:       "cancel",
+			inputs:     []string{"1001", "20", "3", "1"},
+			wantPrompt: "Request cancelled",
+		},
+		{
+			name:       "failed submit is not retried",
+			submitErr:  failing,
+			inputs:     []string{"1001", "20", "1", "1"},
+			wantPrompt: "Your request could not be completed, please try again later",
+			wantCalls:  1,
+			wantValues: map[string]string{"account": "1001", "amount": "20.00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var values map[string]string
+
+			f := NewForm("transfer", func(c *Context, v map[string]string) error {
+				calls++
+				values = v
+				return tt.submitErr
+			}, NumberInput("Account", "account"), AmountInput("Amount", "amount", 1, 500))
+			f.Next = tt.next
+
+			r, c := drive(f, tt.inputs...)
+
+			if tt.wantPrompt != "" && r.Prompt != tt.wantPrompt {
+				t.Errorf("Prompt = %q, want %q", r.Prompt, tt.wantPrompt)
+			}
+			if c.Target != tt.wantTarget {
+				t.Errorf("Target = %q, want %q", c.Target, tt.wantTarget)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Submit called %d times, want %d", calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("values = %v, want %v", values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestFormSecretSummary(t *testing.T) {
+
+	f := NewForm("pin", nil, NumberInput("Account", "account"), PinInput("PIN", "pin", 4))
+
+	r, c := drive(f, "1001", "4321")
+
+	if want := "Please confirm\naccount: 1001\npin: ****"; r.Prompt != want {
+		t.Errorf("Prompt = %q, want %q", r.Prompt, want)
+	}
+	if !c.IsSecret("pin") {
+		t.Error("pin is not stored as a secret")
+	}
+	if !f.DisableGlobalNavigation() {
+		t.Error("form with a secret field takes the global navigation keys")
+	}
+}
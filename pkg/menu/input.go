@@ -0,0 +1,244 @@
+package menu
+
+import (
+	"errors"
+	"github.com/jamesdube/ussd/pkg/i18n"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultRetries = 3
+
+// Validator checks a raw input and returns the value to store, its error
+// message is shown above the prompt.
+type Validator func(msg string) (string, error)
+
+// InputError is a validation error shown translated to the locale of the
+// subscriber.
+type InputError struct {
+	Key  string
+	Args []interface{}
+}
+
+func (e *InputError) Error() string {
+	return i18n.Default().T("en", e.Key, e.Args...)
+}
+
+func inputError(key string, args ...interface{}) error {
+	return &InputError{Key: key, Args: args}
+}
+
+// Input is a menu asking for a single value. Invalid input shows the prompt
+// again prefixed with the validation error, up to Retries times, after which
+// the session ends with Exhausted, the input.exhausted message by default. The
+// value is stored in the Attribute
+// session attribute and the session moves to Next, or to the route of the
+// input when Next is empty.
+type Input struct {
+	Prompt    string
+	Attribute string
+	Validate  Validator
+	Retries   int
+	Exhausted string
+	Next      string
+	// Label names the value in form summaries, the attribute by default.
+	Label string
+	// Secret inputs such as PINs are never taken for the global back and
+	// home keys, and are stored with Context.AddSecret.
+	Secret bool
+}
+
+func NewInput(prompt string, attribute string, v Validator) *Input {
+	return &Input{
+		Prompt:    prompt,
+		Attribute: attribute,
+		Validate:  v,
+		Retries:   defaultRetries,
+	}
+}
+
+func NumberInput(prompt string, attribute string) *Input {
+	return NewInput(prompt, attribute, ValidateNumber)
+}
+
+// AmountInput accepts amounts between min and max, max zero meaning no limit,
+// and stores them with two decimals.
+func AmountInput(prompt string, attribute string, min float64, max float64) *Input {
+	return NewInput(prompt, attribute, ValidateAmount(min, max))
+}
+
+// DateInput accepts dates written in layout and stores them as 2006-01-02.
+func DateInput(prompt string, attribute string, layout string) *Input {
+	return NewInput(prompt, attribute, ValidateDate(layout))
+}
+
+func PinInput(prompt string, attribute string, length int) *Input {
+	i := NewInput(prompt, attribute, ValidatePin(length))
+	i.Secret = true
+	return i
+}
+
+func NationalIdInput(prompt string, attribute string) *Input {
+	return NewInput(prompt, attribute, ValidateNationalId)
+}
+
+// MsisdnInput accepts international numbers and local numbers starting with
+// 0, which are prefixed with countryCode, and stores them without a plus.
+func MsisdnInput(prompt string, attribute string, countryCode string) *Input {
+	return NewInput(prompt, attribute, ValidateMsisdn(countryCode))
+}
+
+func (i *Input) OnRequest(c *Context, msg string) Response {
+
+	if !c.IsReplay() {
+		i.reset(c)
+		return Response{Prompt: i.Prompt}
+	}
+
+	attempts, _ := strconv.Atoi(c.Get(i.key("attempts")))
+	if attempts > i.Retries {
+		i.reset(c)
+		msg := i.Exhausted
+		if msg == "" {
+			msg = c.T("input.exhausted")
+		}
+		return Response{Prompt: msg, NavigationType: Stop}
+	}
+
+	return Response{Prompt: c.Get(i.key("error")) + "\n" + i.Prompt}
+}
+
+func (i *Input) Process(c *Context, msg string) NavigationType {
+
+	v, err := i.Validate(strings.TrimSpace(msg))
+	if err != nil {
+		attempts, _ := strconv.Atoi(c.Get(i.key("attempts")))
+		c.Add(i.key("attempts"), strconv.Itoa(attempts+1))
+		var ie *InputError
+		if errors.As(err, &ie) {
+			c.Add(i.key("error"), c.T(ie.Key, ie.Args...))
+		} else {
+			c.Add(i.key("error"), err.Error())
+		}
+		c.NavigationType = Replay
+		return Replay
+	}
+
+	i.reset(c)
+	if i.Secret {
+		c.AddSecret(i.Attribute, v)
+	} else {
+		c.Add(i.Attribute, v)
+	}
+	if i.Next != "" {
+		c.Goto(i.Next)
+	}
+	return Continue
+}
+
+func (i *Input) DisableGlobalNavigation() bool {
+	return i.Secret
+}
+
+func (i *Input) SecretInput() bool {
+	return i.Secret
+}
+
+func (i *Input) label() string {
+	if i.Label != "" {
+		return i.Label
+	}
+	return i.Attribute
+}
+
+func (i *Input) key(k string) string {
+	return i.Attribute + "." + k
+}
+
+func (i *Input) reset(c *Context) {
+	delete(c.Context, i.key("attempts"))
+	delete(c.Context, i.key("error"))
+}
+
+var (
+	digits     = regexp.MustCompile(`^\d+$`)
+	decimal    = regexp.MustCompile(`^\d+(\.\d+)?$`)
+	nationalId = regexp.MustCompile(`^(\d{2})-?(\d{6,7})-?([A-Z])-?(\d{2})$`)
+	msisdn     = regexp.MustCompile(`^\d{9,15}$`)
+)
+
+func ValidateNumber(msg string) (string, error) {
+	if !digits.MatchString(msg) {
+		return "", inputError("input.number")
+	}
+	return msg, nil
+}
+
+func ValidateAmount(min float64, max float64) Validator {
+	return func(msg string) (string, error) {
+		if !decimal.MatchString(msg) {
+			return "", inputError("input.amount")
+		}
+		a, err := strconv.ParseFloat(msg, 64)
+		if err != nil || a <= 0 {
+			return "", inputError("input.amount")
+		}
+		if max > 0 && (a < min || a > max) {
+			return "", inputError("input.amount_range", min, max)
+		}
+		if a < min {
+			return "", inputError("input.amount_min", min)
+		}
+		return strconv.FormatFloat(a, 'f', 2, 64), nil
+	}
+}
+
+func ValidateDate(layout string) Validator {
+	return func(msg string) (string, error) {
+		d, err := time.Parse(layout, msg)
+		if err != nil {
+			return "", inputError("input.date", dateHint(layout))
+		}
+		return d.Format("2006-01-02"), nil
+	}
+}
+
+func ValidatePin(length int) Validator {
+	return func(msg string) (string, error) {
+		if len(msg) != length || !digits.MatchString(msg) {
+			return "", inputError("input.pin", length)
+		}
+		return msg, nil
+	}
+}
+
+// ValidateNationalId accepts IDs such as 63-123456A78 with or without
+// dashes and stores them without.
+func ValidateNationalId(msg string) (string, error) {
+	m := nationalId.FindStringSubmatch(strings.ToUpper(msg))
+	if m == nil {
+		return "", inputError("input.national_id")
+	}
+	return m[1] + m[2] + m[3] + m[4], nil
+}
+
+func ValidateMsisdn(countryCode string) Validator {
+	return func(msg string) (string, error) {
+		n := strings.TrimPrefix(msg, "+")
+		if countryCode != "" && strings.HasPrefix(n, "0") {
+			n = countryCode + n[1:]
+		}
+		if !msisdn.MatchString(n) {
+			return "", inputError("input.msisdn")
+		}
+		return n, nil
+	}
+}
+
+// dateHint turns a Go layout into the format shown to subscribers.
+func dateHint(layout string) string {
+	r := strings.NewReplacer("2006", "YYYY", "01", "MM", "02", "DD")
+	return r.Replace(layout)
+}
@@ -1,15 +1,25 @@
 package menu
 
-import "github.com/jamesdube/ussd/pkg/session"
+import (
+	"github.com/jamesdube/ussd/pkg/i18n"
+	"github.com/jamesdube/ussd/pkg/session"
+	"sort"
+)
 
 type Menu interface {
 	OnRequest(c *Context, msg string) Response
 	Process(ctx *Context, msg string) NavigationType
 }
 
+// Option is a menu option with an id delivered to Process as
+// Context.Selected, whatever key or page it was shown with.
+type Option = session.Option
+
+// Response is the screen rendered by a menu, Items replace Options when set.
 type Response struct {
 	Prompt         string
 	Options        []string
+	Items          []Option
 	Paginated      bool
 	PerPage        int
 	NavigationType NavigationType
@@ -17,6 +27,7 @@ type Response struct {
 
 type Context struct {
 	Context                  map[string]string
+	SessionId                string
 	Msisdn                   string
 	NavigationType           NavigationType
 	Paginated                bool
@@ -25,16 +36,80 @@ type Context struct {
 	SelectedPaginationOption int
 	SelectedPageOption       int
 	Active                   bool
+	Params                   map[string]string
+	Target                   string
+	Locale                   string
+	Messages                 *i18n.Bundle
+	// Selected is the id of the option picked by the input, empty when the
+	// input matches none. Options given as strings have their position,
+	// counted across pages, as id.
+	Selected string
+	secrets  map[string]bool
 }
 
 func (d *Context) Add(k string, v string) {
 	d.Context[k] = v
 }
 
+// AddSecret stores an attribute, such as a PIN, that is kept out of the admin
+// API, abandoned events and remote applications.
+func (d *Context) AddSecret(k string, v string) {
+	d.Context[k] = v
+	if d.secrets != nil {
+		d.secrets[k] = true
+	}
+}
+
+// public returns the attributes without the secret ones.
+func (d *Context) public() map[string]string {
+	attrs := make(map[string]string, len(d.Context))
+	for k, v := range d.Context {
+		if !d.secrets[k] {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+// IsSecret reports whether the attribute was stored with AddSecret.
+func (d *Context) IsSecret(k string) bool {
+	return d.secrets[k]
+}
+
 func (d *Context) Get(k string) string {
 	return d.Context[k]
 }
 
+// Goto makes the next screen the named menu instead of the one routed from
+// the selection, it is meant to be called from Process.
+func (d *Context) Goto(name string) {
+	d.Target = name
+}
+
+// T returns the message for key in the subscriber's locale, formatted with
+// args.
+func (d *Context) T(key string, args ...interface{}) string {
+	return d.messages().T(d.Locale, key, args...)
+}
+
+// Plural returns the form of the message for key matching the count n.
+func (d *Context) Plural(key string, n int, args ...interface{}) string {
+	return d.messages().Plural(d.Locale, key, n, args...)
+}
+
+func (d *Context) messages() *i18n.Bundle {
+	if d.Messages == nil {
+		return i18n.Default()
+	}
+	return d.Messages
+}
+
+// Param returns the value captured by a named route segment such as
+// {amount:number}.
+func (d *Context) Param(k string) string {
+	return d.Params[k]
+}
+
 func (d *Context) GetData() map[string]string {
 	return d.Context
 }
@@ -56,19 +131,34 @@ func (r *Registry) Find(m string) Menu {
 	return mn
 }
 
+// Names returns the names of the registered menus in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.menus))
+	for n := range r.menus {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func NewRegistry() *Registry {
 	return &Registry{menus: map[string]Menu{}}
 }
 
 func NewContext(msisdn string, session *session.Session) *Context {
+	if session.Secrets == nil {
+		session.Secrets = map[string]bool{}
+	}
 	return &Context{
 		NavigationType: Continue,
 		Context:        session.Attributes,
+		SessionId:      session.Id,
 		Msisdn:         msisdn,
 		Active:         true,
 		Paginated:      session.Paginated,
 		Pages:          session.Pages,
 		CurrentPage:    session.CurrentPage,
+		secrets:        session.Secrets,
 	}
 }
 
@@ -8,8 +8,23 @@ const (
 	Replay    NavigationType = 2
 	Paginated NavigationType = 3
 	LongCode  NavigationType = 4
+	Back      NavigationType = 5
+	Home      NavigationType = 6
 )
 
+// NavigationOptOut is implemented by menus whose input must never be taken
+// for the global back and home keys, such as PIN entry.
+type NavigationOptOut interface {
+	DisableGlobalNavigation() bool
+}
+
+// SecretInput is implemented by menus taking secret input such as PINs. The
+// input is not kept in the session selections, the menu after it is routed
+// on the "*" wildcard, and journeys redact it.
+type SecretInput interface {
+	SecretInput() bool
+}
+
 type Navigation struct {
 	selections []string
 }
@@ -32,3 +47,23 @@ func (n *Navigation) AddToSelections(s string) {
 func (n *Navigation) GetSelections() []string {
 	return n.selections
 }
+
+func (n NavigationType) String() string {
+	switch n {
+	case Continue:
+		return "continue"
+	case Stop:
+		return "stop"
+	case Replay:
+		return "replay"
+	case Paginated:
+		return "paginated"
+	case LongCode:
+		return "long_code"
+	case Back:
+		return "back"
+	case Home:
+		return "home"
+	}
+	return "unknown"
+}
@@ -0,0 +1,169 @@
+package menu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jamesdube/ussd/internal/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultProxyNamespace = "proxy"
+
+// ProxyRequest is posted to the remote application for every hop.
+type ProxyRequest struct {
+	SessionId  string            `json:"sessionId"`
+	Msisdn     string            `json:"msisdn"`
+	Message    string            `json:"message"`
+	New        bool              `json:"new"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// ProxyResponse is the answer of the remote application. Attributes are
+// stored in the namespace of the proxy, End closes it after the prompt.
+type ProxyResponse struct {
+	Prompt     string            `json:"prompt"`
+	Options    []string          `json:"options"`
+	End        bool              `json:"end"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// Proxy is a menu that hands the session over to a remote USSD application
+// over HTTP. Every input is posted to URL while the session stays on the
+// proxy route, until the remote application ends it. The first hop has New
+// set and carries the selection that led to the proxy.
+type Proxy struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+	// Error is shown, ending the session, when the remote application fails,
+	// the proxy.error message by default.
+	Error string
+	// Navigation keeps the global back and home keys working, by default
+	// every input goes to the remote application.
+	Navigation bool
+	// Namespace prefixes the session attributes the remote application can
+	// read and write, "proxy" by default. They are exchanged without the
+	// prefix, so the attribute "proxy.bundle" is seen as "bundle".
+	Namespace string
+	// Share lists session attributes outside the namespace sent to the
+	// remote application, which can not change them.
+	Share []string
+}
+
+func NewProxy(url string) *Proxy {
+	return &Proxy{
+		URL:    url,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (p *Proxy) OnRequest(c *Context, msg string) Response {
+
+	res, err := p.forward(c, msg, !c.IsReplay())
+	if err != nil {
+		utils.Logger.Error("proxy request failed", "url", p.URL, "sessionId", c.SessionId, "error", err)
+		return Response{Prompt: text(c, p.Error, "proxy.error"), NavigationType: Stop}
+	}
+
+	prefix := p.namespace() + "."
+	for k, v := range res.Attributes {
+		if c.Context != nil {
+			c.Add(prefix+k, v)
+		}
+	}
+
+	r := Response{Prompt: res.Prompt, Options: res.Options}
+	if res.End {
+		r.NavigationType = Stop
+	}
+	return r
+}
+
+// Process keeps the session on the proxy route, the input is forwarded when
+// the menu renders again.
+func (p *Proxy) Process(c *Context, msg string) NavigationType {
+	c.NavigationType = Replay
+	return Replay
+}
+
+func (p *Proxy) DisableGlobalNavigation() bool {
+	return !p.Navigation
+}
+
+func (p *Proxy) namespace() string {
+	if p.Namespace == "" {
+		return defaultProxyNamespace
+	}
+	return p.Namespace
+}
+
+// attributes returns the session attributes the remote application may read,
+// those of the namespace without their prefix and the shared ones.
+func (p *Proxy) attributes(c *Context) map[string]string {
+
+	attrs := map[string]string{}
+
+	for _, k := range p.Share {
+		if v, ok := c.Context[k]; ok && !c.IsSecret(k) {
+			attrs[k] = v
+		}
+	}
+
+	prefix := p.namespace() + "."
+	for k, v := range c.Context {
+		if strings.HasPrefix(k, prefix) && !c.IsSecret(k) {
+			attrs[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+
+	return attrs
+}
+
+func (p *Proxy) forward(c *Context, msg string, start bool) (*ProxyResponse, error) {
+
+	body, err := json.Marshal(ProxyRequest{
+		SessionId:  c.SessionId,
+		Msisdn:     c.Msisdn,
+		Message:    msg,
+		New:        start,
+		Attributes: p.attributes(c),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", p.URL, resp.Status)
+	}
+
+	var res ProxyResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
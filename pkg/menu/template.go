@@ -0,0 +1,145 @@
+package menu
+
+import (
+	"fmt"
+	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/i18n"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Funcs are the helpers available to templates.
+var Funcs = template.FuncMap{
+	"currency": currency,
+	"mask":     mask,
+	"truncate": truncate,
+}
+
+// Template is a text/template prompt or option rendered against the menu
+// context. It is parsed and executed against empty data once when created,
+// so a template held in a package variable through MustTemplate fails any
+// test of the package when invalid.
+type Template struct {
+	text string
+	tpl  *template.Template
+}
+
+// TemplateData is what a template is executed against, {{.Attributes.name}}
+// reads a session attribute and {{.T "key"}} a translated message. Secret
+// attributes are left out.
+type TemplateData struct {
+	Attributes map[string]string
+	Params     map[string]string
+	Msisdn     string
+	Locale     string
+	context    *Context
+}
+
+func (d TemplateData) T(key string, args ...interface{}) string {
+	if d.context == nil {
+		return i18n.Default().T(d.Locale, key, args...)
+	}
+	return d.context.T(key, args...)
+}
+
+// NewTemplate parses text and executes it once against empty data, so
+// unknown fields and helpers called with arguments of the wrong type are
+// reported here rather than when a subscriber reaches the menu.
+func NewTemplate(text string) (*Template, error) {
+	tpl, err := template.New("").Funcs(Funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tpl.Execute(&strings.Builder{}, TemplateData{
+		Attributes: map[string]string{},
+		Params:     map[string]string{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{text: text, tpl: tpl}, nil
+}
+
+func MustTemplate(text string) *Template {
+	t, err := NewTemplate(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Execute renders the template for the context.
+func (t *Template) Execute(c *Context) (string, error) {
+
+	var sb strings.Builder
+	err := t.tpl.Execute(&sb, TemplateData{
+		Attributes: c.public(),
+		Params:     c.Params,
+		Msisdn:     c.Msisdn,
+		Locale:     c.Locale,
+		context:    c,
+	})
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Render renders the template for the context, returning an empty string
+// when it fails.
+func (t *Template) Render(c *Context) string {
+	s, err := t.Execute(c)
+	if err != nil {
+		utils.Logger.Error("could not render template", "template", t.text, "error", err)
+		return ""
+	}
+	return s
+}
+
+func (t *Template) String() string {
+	return t.text
+}
+
+// currency formats an amount, given as a number or a string, with two
+// decimals after the currency code.
+func currency(code string, amount interface{}) string {
+	var a float64
+	switch v := amount.(type) {
+	case float64:
+		a = v
+	case int:
+		a = float64(v)
+	case string:
+		a, _ = strconv.ParseFloat(v, 64)
+	default:
+		a, _ = strconv.ParseFloat(fmt.Sprint(v), 64)
+	}
+	return fmt.Sprintf("%s %.2f", code, a)
+}
+
+// mask hides all but the last characters of s, 4 unless given.
+func mask(s string, keep ...int) string {
+	k := 4
+	if len(keep) > 0 {
+		k = keep[0]
+	}
+	if len(s) <= k {
+		return s
+	}
+	return strings.Repeat("*", len(s)-k) + s[len(s)-k:]
+}
+
+// truncate shortens s to n characters, ending with dots when cut.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(r[:n])
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,55 @@
+package menu
+
+import (
+	"github.com/jamesdube/ussd/pkg/session"
+	"testing"
+)
+
+func TestNewTemplate(t *testing.T) {
+
+	tests := []struct {
+		text    string
+		wantErr bool
+	}{
+		{text: "Hello"},
+		{text: "Hello {{.Attributes.name}}"},
+		{text: `{{currency "USD" .Attributes.amount}}`},
+		{text: "Hello {{.Attributes.name", wantErr: true},
+		{text: "Hello {{.Name}}", wantErr: true},
+		{text: "{{unknown .Msisdn}}", wantErr: true},
+		{text: `{{truncate "x" .Msisdn}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := NewTemplate(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewTemplate(%q) error = %v, want error %v", tt.text, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+
+	ss := session.NewSession("s1")
+	c := NewContext("263771234567", ss)
+	c.Add("amount", "12.5")
+	c.Add("name", "{{.Msisdn}}")
+	c.AddSecret("pin", "4321")
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: `Send {{currency "USD" .Attributes.amount}}`, want: "Send USD 12.50"},
+		{text: "{{mask .Msisdn}}", want: "********4567"},
+		{text: "{{.Attributes.name}}", want: "{{.Msisdn}}"},
+		{text: "PIN {{.Attributes.pin}}", want: "PIN "},
+		{text: `{{.T "form.cancel"}}`, want: "Cancel"},
+	}
+
+	for _, tt := range tests {
+		if got := MustTemplate(tt.text).Render(c); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
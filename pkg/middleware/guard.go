@@ -0,0 +1,55 @@
+package middleware
+
+import "github.com/jamesdube/ussd/pkg/menu"
+
+type Action int
+
+const (
+	Allow Action = iota
+	Block
+	Redirect
+)
+
+// Decision is the outcome of a guard. Attributes are added to the session
+// unless the request is blocked, Menu names the redirect target and Message
+// ends a blocked session.
+type Decision struct {
+	Action     Action
+	Menu       string
+	Message    string
+	Attributes map[string]string
+}
+
+// Guard is run once a request has been routed to a menu matching the pattern
+// it is attached to, before the menu renders.
+type Guard interface {
+	Check(c *menu.Context, msg string) Decision
+}
+
+// GuardFunc adapts a function to a Guard.
+type GuardFunc func(c *menu.Context, msg string) Decision
+
+func (f GuardFunc) Check(c *menu.Context, msg string) Decision {
+	return f(c, msg)
+}
+
+func Allowed() Decision {
+	return Decision{Action: Allow}
+}
+
+func Blocked(message string) Decision {
+	return Decision{Action: Block, Message: message}
+}
+
+func RedirectTo(menu string) Decision {
+	return Decision{Action: Redirect, Menu: menu}
+}
+
+// BlockedError is returned when a guard blocks a request.
+type BlockedError struct {
+	Message string
+}
+
+func (e *BlockedError) Error() string {
+	return "blocked by guard: " + e.Message
+}
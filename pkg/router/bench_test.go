@@ -0,0 +1,56 @@
+package router
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchRouter registers n routes three levels deep, mostly literal with a
+// param and a wildcard route under every first level menu.
+func benchRouter(b *testing.B, n int) *Router {
+	b.Helper()
+
+	r := NewRouter()
+	for i := 0; len(r.routes) < n; i++ {
+		add := func(key string) {
+			if len(r.routes) < n {
+				if err := r.AddRoute(key, key, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		add(fmt.Sprintf("*.%d", i))
+		add(fmt.Sprintf("*.%d.{n:number}.confirm", i))
+		add(fmt.Sprintf("*.%d.*.cancel", i))
+		for j := 0; j < 8; j++ {
+			add(fmt.Sprintf("*.%d.%d", i, j))
+			add(fmt.Sprintf("*.%d.%d.%d", i, j, j))
+		}
+	}
+	return r
+}
+
+func BenchmarkResolve(b *testing.B) {
+
+	for _, n := range []int{100, 1000, 5000, 10000} {
+		r := benchRouter(b, n)
+		last := len(r.routes)/19 - 1
+
+		inputs := map[string][]string{
+			"literal":  {"*", fmt.Sprint(last), "7", "7"},
+			"param":    {"*", fmt.Sprint(last), "12345", "confirm"},
+			"wildcard": {"*", fmt.Sprint(last), "abc", "cancel"},
+			"miss":     {"*", fmt.Sprint(last), "abc", "nothing"},
+		}
+
+		for _, kind := range []string{"literal", "param", "wildcard", "miss"} {
+			s := inputs[kind]
+			b.Run(fmt.Sprintf("%d/%s", n, kind), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					r.Resolve(s)
+				}
+			})
+		}
+	}
+}
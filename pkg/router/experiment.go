@@ -0,0 +1,95 @@
+package router
+
+import (
+	"github.com/jamesdube/ussd/pkg/menu"
+	"hash/fnv"
+)
+
+const control = "control"
+
+// Variant is an alternative implementation of a menu served to the
+// subscribers on its allow-list and to Percent percent of the others.
+type Variant struct {
+	Name    string
+	Menu    menu.Menu
+	Percent int
+	Msisdns []string
+}
+
+// Experiment is a menu that serves one of several implementations depending
+// on the subscriber. Buckets are a hash of the experiment name and MSISDN so
+// a subscriber keeps the same variant across sessions, subscribers in no
+// variant get the control menu. The variant is stored in the session
+// attribute "experiment.<name>".
+type Experiment struct {
+	Name     string
+	Control  menu.Menu
+	Variants []Variant
+	// Observe is called when a session is assigned a variant.
+	Observe func(experiment string, variant string)
+}
+
+func NewExperiment(name string, control menu.Menu, variants ...Variant) *Experiment {
+	return &Experiment{Name: name, Control: control, Variants: variants}
+}
+
+func (e *Experiment) OnRequest(c *menu.Context, msg string) menu.Response {
+	return e.pick(c).OnRequest(c, msg)
+}
+
+func (e *Experiment) Process(c *menu.Context, msg string) menu.NavigationType {
+	return e.pick(c).Process(c, msg)
+}
+
+// Variant returns the variant assigned to the session, assigning one when
+// none is.
+func (e *Experiment) Variant(c *menu.Context) string {
+
+	key := "experiment." + e.Name
+	if v := c.Context[key]; v != "" {
+		return v
+	}
+
+	v := e.assign(c.Msisdn)
+	if c.Context != nil {
+		c.Add(key, v)
+	}
+	if e.Observe != nil {
+		e.Observe(e.Name, v)
+	}
+	return v
+}
+
+func (e *Experiment) pick(c *menu.Context) menu.Menu {
+	v := e.Variant(c)
+	for _, vr := range e.Variants {
+		if vr.Name == v {
+			return vr.Menu
+		}
+	}
+	return e.Control
+}
+
+func (e *Experiment) assign(msisdn string) string {
+
+	for _, v := range e.Variants {
+		for _, m := range v.Msisdns {
+			if m == msisdn {
+				return v.Name
+			}
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(e.Name + ":" + msisdn))
+	bucket := int(h.Sum32() % 100)
+
+	for _, v := range e.Variants {
+		if bucket < v.Percent {
+			return v.Name
+		}
+		bucket -= v.Percent
+	}
+
+	return control
+}
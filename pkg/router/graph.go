@@ -0,0 +1,128 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// edge links the route leading to a menu with one of the routes that can
+// follow it.
+type edge struct {
+	from  *Route
+	to    *Route
+	label string
+	wild  bool
+}
+
+// edges returns the links between routes, top level routes have a nil from.
+// A route follows every route whose key covers its parent key.
+func (r *Router) edges() []edge {
+
+	var edges []edge
+	routes := r.Routes()
+
+	for _, rt := range routes {
+		last := rt.segments[len(rt.segments)-1]
+		e := edge{to: rt, label: segmentLabel(last), wild: last.kind != literalSegment}
+
+		if len(rt.segments) == 1 {
+			edges = append(edges, e)
+			continue
+		}
+
+		parent := rt.segments[:len(rt.segments)-1]
+		for _, p := range routes {
+			if covers(p.segments, parent) {
+				e.from = p
+				edges = append(edges, e)
+			}
+		}
+	}
+
+	return edges
+}
+
+func segmentLabel(s segment) string {
+	switch s.kind {
+	case wildcardSegment:
+		return "any"
+	case paramSegment:
+		return "{" + s.name + ":" + s.constraint + "}"
+	}
+	return s.value
+}
+
+func nodeLabel(rt *Route, traffic map[string]float64, nl string) string {
+	l := rt.Name + nl + rt.Key
+	if n, ok := traffic[rt.Key]; ok {
+		l += fmt.Sprintf("%s%.0f hits", nl, n)
+	}
+	return l
+}
+
+// Dot writes the routes as a Graphviz digraph. Each menu is a node labelled
+// with its name and route key, wildcard and param edges are dashed. When
+// traffic holds a count for a route key it is added to the node.
+func (r *Router) Dot(w io.Writer, traffic map[string]float64) error {
+
+	var sb strings.Builder
+
+	sb.WriteString("digraph ussd {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+	sb.WriteString("  dial [label=\"dial\" shape=circle];\n")
+
+	for _, rt := range r.Routes() {
+		fmt.Fprintf(&sb, "  n%d [label=%q];\n", rt.order, nodeLabel(rt, traffic, "\n"))
+	}
+
+	for _, e := range r.edges() {
+		from := "dial"
+		if e.from != nil {
+			from = fmt.Sprintf("n%d", e.from.order)
+		}
+		style := ""
+		if e.wild {
+			style = " style=dashed"
+		}
+		fmt.Fprintf(&sb, "  %s -> n%d [label=%q%s];\n", from, e.to.order, e.label, style)
+	}
+
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Mermaid writes the routes as a Mermaid flowchart, see Dot.
+func (r *Router) Mermaid(w io.Writer, traffic map[string]float64) error {
+
+	var sb strings.Builder
+
+	sb.WriteString("flowchart LR\n")
+	sb.WriteString("  dial((dial))\n")
+
+	for _, rt := range r.Routes() {
+		fmt.Fprintf(&sb, "  n%d[\"%s\"]\n", rt.order, mermaidEscape(nodeLabel(rt, traffic, "<br/>")))
+	}
+
+	for _, e := range r.edges() {
+		from := "dial"
+		if e.from != nil {
+			from = fmt.Sprintf("n%d", e.from.order)
+		}
+		arrow := "-->"
+		if e.wild {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&sb, "  %s %s|\"%s\"| n%d\n", from, arrow, mermaidEscape(e.label), e.to.order)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, "\"", "#quot;")
+}
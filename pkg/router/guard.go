@@ -0,0 +1,69 @@
+package router
+
+import (
+	"fmt"
+	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/middleware"
+)
+
+// guard is a guard attached to a route pattern.
+type guard struct {
+	pattern  string
+	segments []segment
+	guard    middleware.Guard
+}
+
+// Guard attaches g to the route pattern and every route below it, so "*.3"
+// guards the menu at *.3 and the menus reached from it. Patterns use the
+// same segments as route keys.
+func (r *Router) Guard(pattern string, g middleware.Guard) error {
+
+	var segments []segment
+	for _, s := range utils.StringToSlice(pattern) {
+		seg, err := parseSegment(s)
+		if err != nil {
+			return fmt.Errorf("guard %s: %w", pattern, err)
+		}
+		segments = append(segments, seg)
+	}
+
+	r.guards = append(r.guards, guard{pattern: pattern, segments: segments, guard: g})
+	return nil
+}
+
+// Guards returns the guards whose pattern matches the start of the
+// selections, in the order they were attached.
+func (r *Router) Guards(s []string) []middleware.Guard {
+
+	var guards []middleware.Guard
+	for _, g := range r.guards {
+		if g.matches(s) {
+			guards = append(guards, g.guard)
+		}
+	}
+	return guards
+}
+
+func (g guard) matches(s []string) bool {
+
+	if len(s) < len(g.segments) {
+		return false
+	}
+
+	for i, seg := range g.segments {
+		switch seg.kind {
+		case wildcardSegment:
+			continue
+		case paramSegment:
+			if !seg.re.MatchString(s[i]) {
+				return false
+			}
+		default:
+			if seg.value != s[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
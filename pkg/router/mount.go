@@ -0,0 +1,73 @@
+package router
+
+import "github.com/jamesdube/ussd/pkg/middleware"
+
+// Use adds middleware run for requests handled by the routes of r once it is
+// mounted in another router.
+func (r *Router) Use(m middleware.Middleware) {
+	r.middleware = append(r.middleware, m)
+}
+
+// Mount adds the routes and guards of sub under prefix, a route key
+// "airtime.{amount}" in sub becomes "prefix.airtime.{amount}" and the empty
+// key becomes prefix itself. Routes added to sub after mounting are not seen
+// by r.
+func (r *Router) Mount(prefix string, sub *Router) error {
+
+	// guards are only copied once, mounting again refreshes the routes
+	if r.mounted[prefix] != sub {
+		for _, g := range sub.guards {
+			pattern := prefix
+			if g.pattern != "" {
+				pattern = prefix + "." + g.pattern
+			}
+			err := r.Guard(pattern, g.guard)
+			if err != nil {
+				return err
+			}
+		}
+		r.mounted[prefix] = sub
+	}
+
+	for _, rt := range sub.Routes() {
+		key := prefix
+		if rt.Key != "" {
+			key = prefix + "." + rt.Key
+		}
+
+		mounts := append([]*Router{sub}, rt.mounts...)
+		err := r.addRoute(key, rt.Name, rt.Menu, mounts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Middleware returns the middleware of the routers mounting the route that
+// handles the message, or the one it leads to, outermost first.
+func (r *Router) Middleware(s []string, msg string) []middleware.Middleware {
+
+	next := make([]string, len(s), len(s)+1)
+	copy(next, s)
+	next = append(next, msg)
+
+	seen := map[*Router]bool{}
+	var mws []middleware.Middleware
+
+	for _, rt := range []*Route{r.Match(s), r.Match(next)} {
+		if rt == nil {
+			continue
+		}
+		for _, m := range rt.mounts {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			mws = append(mws, m.middleware...)
+		}
+	}
+
+	return mws
+}
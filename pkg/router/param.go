@@ -0,0 +1,92 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// paramTypes are the constraints available to named segments such as
+// {amount:number}.
+var paramTypes = map[string]*regexp.Regexp{
+	"any":    regexp.MustCompile(`^.+$`),
+	"number": regexp.MustCompile(`^\d+$`),
+	"amount": regexp.MustCompile(`^\d+(\.\d{1,2})?$`),
+	"phone":  regexp.MustCompile(`^\+?\d{9,15}$`),
+	"alpha":  regexp.MustCompile(`^[A-Za-z]+$`),
+	"alnum":  regexp.MustCompile(`^[A-Za-z0-9]+$`),
+}
+
+// AddParamType registers a constraint usable as {name:type} in route keys.
+// It must be called before routes using it are added.
+func AddParamType(name string, pattern string) error {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return err
+	}
+	paramTypes[name] = re
+	return nil
+}
+
+type segmentKind int
+
+const (
+	literalSegment segmentKind = iota
+	wildcardSegment
+	paramSegment
+)
+
+// segment is a parsed part of a route key. Params are written {name},
+// {name:type} or {name:/regex/}; regular expressions cannot contain dots as
+// keys are split on them.
+type segment struct {
+	kind       segmentKind
+	value      string
+	name       string
+	constraint string
+	re         *regexp.Regexp
+}
+
+func parseSegment(s string) (segment, error) {
+
+	if s == wildcard {
+		return segment{kind: wildcardSegment, value: s}, nil
+	}
+
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return segment{kind: literalSegment, value: s}, nil
+	}
+
+	name, constraint := s[1:len(s)-1], "any"
+	if i := strings.Index(name, ":"); i >= 0 {
+		name, constraint = name[:i], name[i+1:]
+	}
+
+	if name == "" {
+		return segment{}, fmt.Errorf("parameter %s has no name", s)
+	}
+
+	var re *regexp.Regexp
+	if len(constraint) > 1 && strings.HasPrefix(constraint, "/") && strings.HasSuffix(constraint, "/") {
+		var err error
+		re, err = regexp.Compile("^(?:" + constraint[1:len(constraint)-1] + ")$")
+		if err != nil {
+			return segment{}, fmt.Errorf("parameter %s: %w", s, err)
+		}
+	} else {
+		re = paramTypes[constraint]
+		if re == nil {
+			return segment{}, fmt.Errorf("parameter %s has unknown type %s", s, constraint)
+		}
+	}
+
+	return segment{kind: paramSegment, value: s, name: name, constraint: constraint, re: re}, nil
+}
+
+// paramEdge leads to the subtree of a param constraint, params sharing a
+// constraint share the edge whatever their name.
+type paramEdge struct {
+	constraint string
+	re         *regexp.Regexp
+	next       *node
+}
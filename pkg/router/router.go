@@ -1,66 +1,275 @@
 package router
 
 import (
+	"fmt"
 	"github.com/jamesdube/ussd/internal/utils"
 	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/middleware"
+	"sort"
 )
 
+const wildcard = "*"
+
+// Router resolves selections to menus through a trie of route segments.
+// When several routes match, literal segments beat params and params beat
+// wildcards from left to right, so the route with the longer literal prefix
+// wins. Params are tried in registration order and input they reject falls
+// through to the other routes.
 type Router struct {
-	menus  []menu.Menu
-	routes map[string]menu.Menu
+	menus      []menu.Menu
+	routes     map[string]*Route
+	root       *node
+	middleware []middleware.Middleware
+	guards     []guard
+	mounted    map[string]*Router
+}
+
+// Route is a menu registered under a route key.
+type Route struct {
+	Key      string
+	Name     string
+	Menu     menu.Menu
+	segments []segment
+	order    int
+	mounts   []*Router
+}
+
+// node is a trie node, children are keyed by literal segment.
+type node struct {
+	children map[string]*node
+	params   []*paramEdge
+	wildcard *node
+	route    *Route
+}
+
+func newNode() *node {
+	return &node{children: map[string]*node{}}
+}
+
+// AmbiguousRouteError is returned when a route key, or one matching exactly
+// the same input, is registered for two different menus.
+type AmbiguousRouteError struct {
+	Key      string
+	Existing string
+	Name     string
+}
+
+func (e *AmbiguousRouteError) Error() string {
+	return fmt.Sprintf("route %s is ambiguous between menus %s and %s", e.Key, e.Existing, e.Name)
 }
 
 func NewRouter() *Router {
 	return &Router{
-		menus:  []menu.Menu{},
-		routes: map[string]menu.Menu{},
+		menus:   []menu.Menu{},
+		routes:  map[string]*Route{},
+		root:    newNode(),
+		mounted: map[string]*Router{},
 	}
 }
 
-func (r *Router) AddRoute(route string, menu menu.Menu) {
-	r.routes[route] = menu
+func (r *Router) AddRoute(route string, name string, menu menu.Menu) error {
+	return r.addRoute(route, name, menu, nil)
 }
 
-func (r *Router) RouteTo(s []string) menu.Menu {
+func (r *Router) addRoute(route string, name string, menu menu.Menu, mounts []*Router) error {
 
-	for k, m := range r.routes {
-		route := utils.StringToSlice(k)
+	if existing, ok := r.routes[route]; ok {
+		if existing.Name != name {
+			return &AmbiguousRouteError{Key: route, Existing: existing.Name, Name: name}
+		}
+		existing.Menu = menu
+		existing.mounts = mounts
+		return nil
+	}
 
-		found := check(route, s)
-		if found {
-			utils.Logger.Debug("routing to ", "route", k, "selection", s)
-			return m
+	var segments []segment
+	for _, s := range utils.StringToSlice(route) {
+		seg, err := parseSegment(s)
+		if err != nil {
+			return fmt.Errorf("route %s: %w", route, err)
 		}
+		segments = append(segments, seg)
+	}
+
+	rt := &Route{
+		Key:      route,
+		Name:     name,
+		Menu:     menu,
+		segments: segments,
+		order:    len(r.routes),
+		mounts:   mounts,
+	}
+
+	err := r.root.insert(segments, rt)
+	if err != nil {
+		return err
 	}
+	r.routes[route] = rt
+
 	return nil
 }
 
-func check(k []string, v []string) bool {
+func (r *Router) RouteTo(s []string) menu.Menu {
+	rt := r.Match(s)
+	if rt == nil {
+		return nil
+	}
+	return rt.Menu
+}
 
-	if len(k) != len(v) {
-		return false
+// Match returns the route matching the selections, or nil when none does.
+func (r *Router) Match(s []string) *Route {
+	rt, _ := r.Resolve(s)
+	return rt
+}
+
+// Resolve returns the route matching the selections along with the values
+// captured by its params.
+func (r *Router) Resolve(s []string) (*Route, map[string]string) {
+
+	rt := r.root.match(s)
+	if rt == nil {
+		return nil, nil
 	}
 
-	for i := 0; i < len(k); i++ {
-		match := comparator(k[i], v[i])
+	utils.Logger.Debug("routing to ", "route", rt.Key, "selection", s)
 
-		if !match {
-			return false
+	params := map[string]string{}
+	for i, seg := range rt.segments {
+		if seg.kind == paramSegment {
+			params[seg.name] = s[i]
 		}
 	}
 
-	return true
+	return rt, params
 }
 
-func comparator(v1 string, v2 string) bool {
+func (n *node) insert(segments []segment, rt *Route) error {
 
-	if v1 == v2 {
-		return true
+	if len(segments) == 0 {
+		if n.route != nil && n.route.Name != rt.Name {
+			return &AmbiguousRouteError{Key: rt.Key, Existing: n.route.Name, Name: rt.Name}
+		}
+		n.route = rt
+		return nil
 	}
 
-	if v1 == "*" {
+	seg := segments[0]
+
+	var next *node
+	switch seg.kind {
+	case wildcardSegment:
+		if n.wildcard == nil {
+			n.wildcard = newNode()
+		}
+		next = n.wildcard
+	case paramSegment:
+		for _, p := range n.params {
+			if p.constraint == seg.constraint {
+				next = p.next
+			}
+		}
+		if next == nil {
+			next = newNode()
+			n.params = append(n.params, &paramEdge{constraint: seg.constraint, re: seg.re, next: next})
+		}
+	default:
+		next = n.children[seg.value]
+		if next == nil {
+			next = newNode()
+			n.children[seg.value] = next
+		}
+	}
+
+	return next.insert(segments[1:], rt)
+}
+
+// match walks literal children before the wildcard so the first route found
+// is the one with the most specific prefix.
+func (n *node) match(s []string) *Route {
+
+	if len(s) == 0 {
+		return n.route
+	}
+
+	if next, ok := n.children[s[0]]; ok {
+		if rt := next.match(s[1:]); rt != nil {
+			return rt
+		}
+	}
+
+	for _, p := range n.params {
+		if p.re.MatchString(s[0]) {
+			if rt := p.next.match(s[1:]); rt != nil {
+				return rt
+			}
+		}
+	}
+
+	if n.wildcard != nil {
+		return n.wildcard.match(s[1:])
+	}
+
+	return nil
+}
+
+// Routes returns the registered routes in registration order.
+func (r *Router) Routes() []*Route {
+
+	routes := make([]*Route, 0, len(r.routes))
+	for _, rt := range r.routes {
+		routes = append(routes, rt)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].order < routes[j].order
+	})
+	return routes
+}
+
+// Reachable reports whether the menu leading to rt is routed, that is some
+// route accepts every input its parent key accepts. Top level routes are
+// always reachable.
+func (r *Router) Reachable(rt *Route) bool {
+
+	if len(rt.segments) <= 1 {
 		return true
 	}
 
+	parent := rt.segments[:len(rt.segments)-1]
+	for _, p := range r.routes {
+		if covers(p.segments, parent) {
+			return true
+		}
+	}
 	return false
 }
+
+// covers reports whether a route with segments p matches all input matched
+// by segments q.
+func covers(p []segment, q []segment) bool {
+
+	if len(p) != len(q) {
+		return false
+	}
+
+	for i := range p {
+		switch p[i].kind {
+		case wildcardSegment:
+			continue
+		case paramSegment:
+			if q[i].kind == paramSegment && q[i].constraint == p[i].constraint {
+				continue
+			}
+			if q[i].kind == literalSegment && p[i].re.MatchString(q[i].value) {
+				continue
+			}
+			return false
+		default:
+			if q[i].kind != literalSegment || q[i].value != p[i].value {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,222 @@
+package router
+
+import (
+	"errors"
+	"github.com/jamesdube/ussd/internal/utils"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	utils.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+// route is a route key registered for a menu name.
+type route struct {
+	key  string
+	name string
+}
+
+func newTestRouter(t testing.TB, routes []route) *Router {
+	t.Helper()
+	r := NewRouter()
+	for _, rt := range routes {
+		if err := r.AddRoute(rt.key, rt.name, nil); err != nil {
+			t.Fatalf("AddRoute(%q, %q): %v", rt.key, rt.name, err)
+		}
+	}
+	return r
+}
+
+func TestMatch(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		routes []route
+		input  []string
+		want   string
+	}{
+		{
+			name:   "literal",
+			routes: []route{{"*.1", "balance"}},
+			input:  []string{"*", "1"},
+			want:   "balance",
+		},
+		{
+			name:   "no match",
+			routes: []route{{"*.1", "balance"}},
+			input:  []string{"*", "2"},
+		},
+		{
+			name:   "no match on longer input",
+			routes: []route{{"*.1", "balance"}},
+			input:  []string{"*", "1", "1"},
+		},
+		{
+			name:   "wildcard",
+			routes: []route{{"*.*", "any"}},
+			input:  []string{"*", "7"},
+			want:   "any",
+		},
+		{
+			name:   "literal beats wildcard registered first",
+			routes: []route{{"*.*", "any"}, {"*.1", "balance"}},
+			input:  []string{"*", "1"},
+			want:   "balance",
+		},
+		{
+			name:   "literal beats param registered first",
+			routes: []route{{"*.{n:number}", "number"}, {"*.1", "balance"}},
+			input:  []string{"*", "1"},
+			want:   "balance",
+		},
+		{
+			name:   "param beats wildcard registered first",
+			routes: []route{{"*.*", "any"}, {"*.{n:number}", "number"}},
+			input:  []string{"*", "42"},
+			want:   "number",
+		},
+		{
+			name:   "rejected param falls through to wildcard",
+			routes: []route{{"*.*", "any"}, {"*.{n:number}", "number"}},
+			input:  []string{"*", "abc"},
+			want:   "any",
+		},
+		{
+			name:   "longer literal prefix wins",
+			routes: []route{{"*.*.1", "wild"}, {"*.2.*", "literal"}},
+			input:  []string{"*", "2", "1"},
+			want:   "literal",
+		},
+		{
+			name:   "literal prefix without a route falls back",
+			routes: []route{{"*.*.1", "wild"}, {"*.2.3", "literal"}},
+			input:  []string{"*", "2", "1"},
+			want:   "wild",
+		},
+		{
+			name:   "params tie in registration order",
+			routes: []route{{"*.{n:number}", "number"}, {"*.{a:amount}", "amount"}},
+			input:  []string{"*", "5"},
+			want:   "number",
+		},
+		{
+			name:   "params tie in registration order reversed",
+			routes: []route{{"*.{a:amount}", "amount"}, {"*.{n:number}", "number"}},
+			input:  []string{"*", "5"},
+			want:   "amount",
+		},
+		{
+			name:   "later param matches when the first has no route",
+			routes: []route{{"*.{n:number}.x", "number"}, {"*.{a:amount}.y", "amount"}},
+			input:  []string{"*", "5", "y"},
+			want:   "amount",
+		},
+		{
+			name:   "regex param",
+			routes: []route{{"*.{code:/[A-Z]{3}/}", "code"}, {"*.*", "any"}},
+			input:  []string{"*", "USD"},
+			want:   "code",
+		},
+		{
+			name:   "regex param rejects",
+			routes: []route{{"*.{code:/[A-Z]{3}/}", "code"}, {"*.*", "any"}},
+			input:  []string{"*", "usd"},
+			want:   "any",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, tt.routes)
+
+			rt := r.Match(tt.input)
+			var got string
+			if rt != nil {
+				got = rt.Name
+			}
+			if got != tt.want {
+				t.Errorf("Match(%v) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveParams(t *testing.T) {
+
+	r := newTestRouter(t, []route{{"*.{account:number}.{amount:amount}", "pay"}})
+
+	rt, params := r.Resolve([]string{"*", "1001", "12.50"})
+	if rt == nil || rt.Name != "pay" {
+		t.Fatalf("Resolve() = %v, want pay", rt)
+	}
+	if params["account"] != "1001" || params["amount"] != "12.50" {
+		t.Errorf("params = %v", params)
+	}
+}
+
+func TestAddRouteAmbiguous(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		routes []route
+		add    route
+	}{
+		{
+			name:   "same key",
+			routes: []route{{"*.1", "balance"}},
+			add:    route{"*.1", "airtime"},
+		},
+		{
+			name:   "params of the same type",
+			routes: []route{{"*.{account}", "account"}},
+			add:    route{"*.{name}", "name"},
+		},
+		{
+			name:   "params of the same type and name",
+			routes: []route{{"*.{n:number}", "number"}},
+			add:    route{"*.{n:number}", "other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, tt.routes)
+
+			err := r.AddRoute(tt.add.key, tt.add.name, nil)
+
+			var ambiguous *AmbiguousRouteError
+			if !errors.As(err, &ambiguous) {
+				t.Fatalf("AddRoute(%q) error = %v, want AmbiguousRouteError", tt.add.key, err)
+			}
+			if ambiguous.Name != tt.add.name {
+				t.Errorf("Name = %q, want %q", ambiguous.Name, tt.add.name)
+			}
+		})
+	}
+}
+
+func TestAddRouteSameMenu(t *testing.T) {
+
+	r := newTestRouter(t, []route{{"*.1", "balance"}})
+
+	if err := r.AddRoute("*.1", "balance", nil); err != nil {
+		t.Fatalf("re-adding a route for the same menu: %v", err)
+	}
+	if n := len(r.Routes()); n != 1 {
+		t.Errorf("len(Routes()) = %d, want 1", n)
+	}
+}
+
+func TestAddRouteInvalidParam(t *testing.T) {
+
+	for _, key := range []string{"*.{}", "*.{n:unknown}", "*.{n:/(/}"} {
+		r := NewRouter()
+		if err := r.AddRoute(key, "bad", nil); err == nil {
+			t.Errorf("AddRoute(%q) succeeded, want an error", key)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"fmt"
+)
+
+// Lookup returns the route of a menu by name, the first registered when the
+// menu is reachable through several keys.
+func (r *Router) Lookup(name string) *Route {
+
+	var found *Route
+	for _, rt := range r.routes {
+		if rt.Name == name && (found == nil || rt.order < found.order) {
+			found = rt
+		}
+	}
+	return found
+}
+
+// PathTo returns the selections leading to the named menu. Wildcards and
+// params are filled from the current selections at the same position, so
+// a transition keeps the dialled short code and previously captured values.
+func (r *Router) PathTo(name string, current []string) ([]string, error) {
+
+	rt := r.Lookup(name)
+	if rt == nil {
+		return nil, fmt.Errorf("no route to menu %s", name)
+	}
+
+	path := make([]string, len(rt.segments))
+	for i, seg := range rt.segments {
+
+		if seg.kind == literalSegment {
+			path[i] = seg.value
+			continue
+		}
+
+		if i >= len(current) || (seg.kind == paramSegment && !seg.re.MatchString(current[i])) {
+			return nil, fmt.Errorf("no value for segment %s of route %s to menu %s", seg.value, rt.Key, name)
+		}
+		path[i] = current[i]
+	}
+
+	return path, nil
+}
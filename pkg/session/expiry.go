@@ -0,0 +1,11 @@
+package session
+
+// ExpiryListener is called with the last saved state of a session that
+// expired in the store without being deleted.
+type ExpiryListener func(s *Session)
+
+// Expirer is implemented by repositories that can report sessions expiring
+// in the underlying store.
+type Expirer interface {
+	OnExpire(l ExpiryListener)
+}
@@ -5,119 +5,282 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/hazelcast/hazelcast-go-client"
+	"github.com/hazelcast/hazelcast-go-client/cluster"
+	"github.com/hazelcast/hazelcast-go-client/types"
 	"github.com/jamesdube/ussd/internal/config"
 	"github.com/jamesdube/ussd/internal/utils"
-	"go.uber.org/zap"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const mapKey = "ussd-sessions"
 
+const defaultHazelcastTTL = 60 * time.Second
+
+// claimTTL is how long the claim of an expired session is kept, long enough
+// for every client to have received the expiry event.
+const claimTTL = 10 * time.Minute
+
 type HazelcastRepository struct {
 	client *hazelcast.Client
+	sMap   *hazelcast.Map
+	claims *hazelcast.Map
+	ttl    time.Duration
+}
+
+type HazelcastConfig struct {
+	Cluster        string
+	Addrs          []string
+	Map            string
+	Username       string
+	Password       string
+	CloudToken     string `yaml:"cloudToken"`
+	UsePublicIP    bool   `yaml:"usePublicIP"`
+	TLS            TLSConfig
+	ConnectTimeout time.Duration `yaml:"connectTimeout"`
+	// ReconnectTimeout bounds how long the client keeps reconnecting after
+	// losing the cluster, zero retries forever.
+	ReconnectTimeout time.Duration `yaml:"reconnectTimeout"`
 }
 
-func NewHazelCast(name string) *HazelcastRepository {
+// NewHazelCast connects to the cluster described by c, overridden by the
+// HAZELCAST_* environment variables. Sessions expire after SESSION_TTL
+// seconds, 60 when unset.
+func NewHazelCast(c HazelcastConfig) (*HazelcastRepository, error) {
 
-	host := config.Get("HAZELCAST_HOST")
-	portS := config.Get("HAZELCAST_PORT")
-	port, _ := strconv.Atoi(portS)
+	c = hazelcastFromEnv(c)
 
 	cfg := hazelcast.Config{}
 	cc := &cfg.Cluster
-	cc.Network.SetAddresses(fmt.Sprintf("%s:%d", host, port))
-	cc.Name = name
+	cc.Name = c.Cluster
+	cc.Network.SetAddresses(c.Addrs...)
+	cc.Network.ConnectionTimeout = types.Duration(c.ConnectTimeout)
+	cc.Security.Credentials.Username = c.Username
+	cc.Security.Credentials.Password = c.Password
+	cc.Discovery.UsePublicIP = c.UsePublicIP
+	cc.ConnectionStrategy.ReconnectMode = cluster.ReconnectModeOn
+	cc.ConnectionStrategy.Timeout = types.Duration(c.ReconnectTimeout)
+
+	if c.CloudToken != "" {
+		cc.Cloud.Enabled = true
+		cc.Cloud.Token = c.CloudToken
+	}
+
+	tc, err := c.TLS.build()
+	if err != nil {
+		return nil, fmt.Errorf("hazelcast tls: %w", err)
+	}
+	if tc != nil {
+		cc.Network.SSL.Enabled = true
+		cc.Network.SSL.SetTLSConfig(tc)
+	}
+
+	ctx := context.TODO()
+
+	client, err := hazelcast.StartNewClientWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("hazelcast %v: %w", c.Addrs, err)
+	}
+
+	_, err = client.AddLifecycleListener(func(event hazelcast.LifecycleStateChanged) {
+		switch event.State {
+		case hazelcast.LifecycleStateDisconnected:
+			utils.Logger.Warn("disconnected from hazelcast cluster, reconnecting", "cluster", c.Cluster)
+		case hazelcast.LifecycleStateConnected:
+			utils.Logger.Info("connected to hazelcast cluster", "cluster", c.Cluster)
+		}
+	})
+	if err != nil {
+		utils.Logger.Warn("could not watch hazelcast connection", "error", err)
+	}
+
+	sMap, err := client.GetMap(ctx, c.Map)
+	if err != nil {
+		client.Shutdown(ctx)
+		return nil, fmt.Errorf("hazelcast map %s: %w", c.Map, err)
+	}
 
-	client, err := hazelcast.StartNewClientWithConfig(context.TODO(), cfg)
+	claims, err := client.GetMap(ctx, c.Map+"-expired")
 	if err != nil {
-		zap.Error(err)
-		return nil
+		client.Shutdown(ctx)
+		return nil, fmt.Errorf("hazelcast map %s-expired: %w", c.Map, err)
+	}
+
+	ttl := defaultHazelcastTTL
+	if s, _ := strconv.Atoi(config.Get("SESSION_TTL")); s > 0 {
+		ttl = time.Duration(s) * time.Second
 	}
 
 	return &HazelcastRepository{
 		client: client,
-	}
+		sMap:   sMap,
+		claims: claims,
+		ttl:    ttl,
+	}, nil
 }
 
-func (h *HazelcastRepository) GetSession(id string) (*Session, error) {
+func hazelcastFromEnv(c HazelcastConfig) HazelcastConfig {
 
-	ctx := context.TODO()
-	hMap, e := h.client.GetMap(context.TODO(), mapKey)
+	if host := config.Get("HAZELCAST_HOST"); host != "" {
+		port := config.Get("HAZELCAST_PORT")
+		if port == "" {
+			port = "5701"
+		}
+		c.Addrs = []string{fmt.Sprintf("%s:%s", host, port)}
+	} else if addrs := config.Get("HAZELCAST_ADDRS"); addrs != "" {
+		c.Addrs = strings.Split(addrs, ",")
+	}
+
+	if v := config.Get("HAZELCAST_CLUSTER"); v != "" {
+		c.Cluster = v
+	}
+	if v := config.Get("HAZELCAST_USERNAME"); v != "" {
+		c.Username = v
+	}
+	if v := config.Get("HAZELCAST_PASSWORD"); v != "" {
+		c.Password = v
+	}
+	if v := config.Get("HAZELCAST_CLOUD_TOKEN"); v != "" {
+		c.CloudToken = v
+	}
 
-	if e != nil {
-		zap.Error(e)
-		return nil, e
+	if c.Cluster == "" {
+		c.Cluster = "ussd"
+	}
+	if c.Map == "" {
+		c.Map = mapKey
 	}
 
-	key, err := hMap.ContainsKey(ctx, id)
+	return c
+}
+
+func (h *HazelcastRepository) GetSession(id string) (*Session, error) {
+
+	data, err := h.sMap.Get(context.TODO(), id)
 	if err != nil {
-		zap.Error(err)
+		utils.Logger.Error(err.Error())
 		return nil, err
 	}
 
-	if !key {
+	if data == nil {
 		return NewSession(id), nil
 	}
 
-	data, err := hMap.Get(ctx, id)
+	return toSession(data)
+}
+
+func (h *HazelcastRepository) Save(s *Session) error {
+
+	j, err := ToJson(s)
+	if err != nil {
+		return err
+	}
+
+	err = h.sMap.SetWithTTL(context.TODO(), s.Id, j, h.ttl)
 	if err != nil {
-		zap.Error(err)
-		return nil, err
+		utils.Logger.Error(err.Error())
+		return err
 	}
 
-	var sess Session
+	return nil
 
-	b, err := json.Marshal(data)
+}
+
+func (h *HazelcastRepository) Delete(id string) {
+
+	err := h.sMap.Delete(context.TODO(), id)
 	if err != nil {
 		utils.Logger.Error(err.Error())
-		return nil, err
+		return
 	}
-	s := string(b)
 
-	err = FromJson(s, &sess)
+}
+
+func (h *HazelcastRepository) List() ([]*Session, error) {
+
+	values, err := h.sMap.GetValues(context.TODO())
 	if err != nil {
-		zap.Error(err)
+		utils.Logger.Error(err.Error())
 		return nil, err
 	}
 
-	return &sess, nil
+	var sessions []*Session
+	for _, v := range values {
+		s, err := toSession(v)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, nil
 }
 
-func (h *HazelcastRepository) Save(s *Session) error {
+// OnExpire registers a map listener that calls l with the last state of every
+// session evicted by the map TTL. Each connected client receives the event,
+// only the one claiming the session calls l.
+func (h *HazelcastRepository) OnExpire(l ExpiryListener) {
 
-	ctx := context.TODO()
-	hMap, e := h.client.GetMap(ctx, mapKey)
+	_, err := h.sMap.AddListener(context.TODO(), hazelcast.MapListener{
+		EntryExpired: func(event *hazelcast.EntryNotified) {
+			data := event.OldValue
+			if data == nil {
+				data = event.Value
+			}
 
-	if e != nil {
-		utils.Logger.Error(e.Error())
-		return e
-	}
+			if !h.claimExpired(event.Key) {
+				return
+			}
+
+			s, err := toSession(data)
+			if err != nil {
+				utils.Logger.Error("could not parse expired session", "key", event.Key, "error", err)
+				return
+			}
+			l(s)
+		},
+	}, true)
 
-	err := hMap.SetWithTTL(ctx, s.Id, s, time.Duration(60)*time.Second)
 	if err != nil {
-		utils.Logger.Error(e.Error())
-		return err
+		utils.Logger.Error(err.Error())
 	}
+}
 
-	return nil
+// claimExpired reports whether this client is the first to claim the expired
+// session key.
+func (h *HazelcastRepository) claimExpired(key interface{}) bool {
 
+	prev, err := h.claims.PutIfAbsentWithTTL(context.TODO(), key, true, claimTTL)
+	if err != nil {
+		utils.Logger.Error("could not claim expired session", "key", key, "error", err)
+		return false
+	}
+
+	return prev == nil
 }
 
-func (h *HazelcastRepository) Delete(id string) {
+// toSession decodes a map value, sessions are stored as JSON but entries
+// written by older versions hold the serialized struct.
+func toSession(data interface{}) (*Session, error) {
 
-	ctx := context.TODO()
-	hMap, e := h.client.GetMap(ctx, mapKey)
+	var sess Session
 
-	if e != nil {
-		utils.Logger.Error(e.Error())
-		return
+	j, ok := data.(string)
+	if !ok {
+		b, err := json.Marshal(data)
+		if err != nil {
+			utils.Logger.Error(err.Error())
+			return nil, err
+		}
+		j = string(b)
 	}
 
-	err := hMap.Delete(ctx, id)
+	err := FromJson(j, &sess)
 	if err != nil {
 		utils.Logger.Error(err.Error())
-		return
+		return nil, err
 	}
 
+	return &sess, nil
 }
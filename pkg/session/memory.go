@@ -1,16 +1,35 @@
 package session
 
-import "sync"
+import (
+	"github.com/jamesdube/ussd/internal/config"
+	"strconv"
+	"sync"
+	"time"
+)
 
 type InMemory struct {
-	sessions map[string]*Session
-	mu       sync.RWMutex
+	sessions  map[string]*Session
+	expiries  map[string]time.Time
+	ttl       time.Duration
+	listeners []ExpiryListener
+	mu        sync.RWMutex
 }
 
 func NewInMemory() *InMemory {
-	return &InMemory{
+
+	ttl, _ := strconv.Atoi(config.Get("SESSION_TTL"))
+
+	im := &InMemory{
 		sessions: map[string]*Session{},
+		expiries: map[string]time.Time{},
+		ttl:      time.Second * time.Duration(ttl),
 	}
+
+	if im.ttl > 0 {
+		go im.janitor()
+	}
+
+	return im
 }
 
 func (im *InMemory) AddSelection(s string) {
@@ -18,14 +37,15 @@ func (im *InMemory) AddSelection(s string) {
 }
 
 func (im *InMemory) GetSession(id string) (*Session, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
 
-	for _, s := range im.sessions {
-		if s.GetID() == id {
-			return s, nil
-		}
+	s, ok := im.sessions[id]
+	if !ok || im.expired(id, time.Now()) {
+		return NewSession(id), nil
 	}
 
-	return NewSession(id), nil
+	return s, nil
 
 }
 
@@ -33,6 +53,9 @@ func (im *InMemory) Save(s *Session) error {
 	im.mu.Lock()
 	defer im.mu.Unlock()
 	im.sessions[s.GetID()] = s
+	if im.ttl > 0 {
+		im.expiries[s.GetID()] = time.Now().Add(im.ttl)
+	}
 	return nil
 }
 
@@ -40,4 +63,65 @@ func (im *InMemory) Delete(id string) {
 	im.mu.Lock()
 	defer im.mu.Unlock()
 	delete(im.sessions, id)
+	delete(im.expiries, id)
+}
+
+func (im *InMemory) List() ([]*Session, error) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	now := time.Now()
+	var sessions []*Session
+	for id, s := range im.sessions {
+		if !im.expired(id, now) {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+// OnExpire registers a listener called by the janitor for every session
+// whose SESSION_TTL elapsed without it being deleted.
+func (im *InMemory) OnExpire(l ExpiryListener) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	im.listeners = append(im.listeners, l)
+}
+
+func (im *InMemory) expired(id string, now time.Time) bool {
+	e, ok := im.expiries[id]
+	return ok && now.After(e)
+}
+
+func (im *InMemory) janitor() {
+
+	interval := im.ttl / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	for range time.Tick(interval) {
+		im.sweep(time.Now())
+	}
+}
+
+func (im *InMemory) sweep(now time.Time) {
+
+	im.mu.Lock()
+	var expired []*Session
+	for id, s := range im.sessions {
+		if im.expired(id, now) {
+			expired = append(expired, s)
+			delete(im.sessions, id)
+			delete(im.expiries, id)
+		}
+	}
+	listeners := im.listeners
+	im.mu.Unlock()
+
+	for _, s := range expired {
+		for _, l := range listeners {
+			l(s)
+		}
+	}
 }
@@ -0,0 +1,13 @@
+package session
+
+// Option is a menu option as kept in the session between the screen showing
+// it and the request selecting it. Key is what the subscriber types, the
+// position on the page when empty. Hidden options are not shown but can be
+// selected by their key, disabled options are shown but cannot be selected.
+type Option struct {
+	Id       string `json:"id"`
+	Label    string `json:"label"`
+	Key      string `json:"key,omitempty"`
+	Hidden   bool   `json:"hidden,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
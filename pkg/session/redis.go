@@ -5,34 +5,137 @@ import (
 	"fmt"
 	"github.com/go-redis/redis"
 	"github.com/jamesdube/ussd/internal/config"
+	"github.com/jamesdube/ussd/internal/utils"
 	"log"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	keyPrefix  = "sessions::"
+	lastSuffix = "::last"
+)
+
 type Redis struct {
-	client *redis.Client
+	client redis.UniversalClient
 	ttl    int
+	db     int
+}
+
+// RedisConfig selects a single node, Sentinel (MasterName set) or Cluster
+// (several Addrs) deployment.
+type RedisConfig struct {
+	Addrs        []string
+	MasterName   string `yaml:"masterName"`
+	Username     string
+	Password     string
+	DB           int
+	TLS          TLSConfig
+	Pool         RedisPoolConfig
+	DialTimeout  time.Duration `yaml:"dialTimeout"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
 }
 
-func NewRedis() *Redis {
+type RedisPoolConfig struct {
+	Size        int
+	MinIdle     int `yaml:"minIdle"`
+	Timeout     time.Duration
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+	MaxConnAge  time.Duration `yaml:"maxConnAge"`
+}
 
-	redisHost := config.Get("REDIS_HOST")
-	redisPort := config.Get("REDIS_PORT")
-	redisDB := config.Get("REDIS_DB")
-	ttl := config.Get("SESSION_TTL")
+// NewRedis connects to Redis using c overridden by the REDIS_* environment
+// variables and verifies the connection.
+func NewRedis(c RedisConfig) (*Redis, error) {
 
-	db, _ := strconv.Atoi(redisDB)
+	c = redisFromEnv(c)
+	ttl, _ := strconv.Atoi(config.Get("SESSION_TTL"))
 
-	c := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
-		Password: "", // no password set
-		DB:       db, // use default DB
-	})
+	tc, err := c.TLS.build()
+	if err != nil {
+		return nil, fmt.Errorf("redis tls: %w", err)
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:        c.Addrs,
+		MasterName:   c.MasterName,
+		Password:     c.Password,
+		DB:           c.DB,
+		TLSConfig:    tc,
+		PoolSize:     c.Pool.Size,
+		MinIdleConns: c.Pool.MinIdle,
+		PoolTimeout:  c.Pool.Timeout,
+		IdleTimeout:  c.Pool.IdleTimeout,
+		MaxConnAge:   c.Pool.MaxConnAge,
+		DialTimeout:  c.DialTimeout,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+	}
+
+	// ACL users need the two argument form of AUTH
+	if c.Username != "" {
+		opts.Password = ""
+		opts.OnConnect = func(cn *redis.Conn) error {
+			return cn.Do("AUTH", c.Username, c.Password).Err()
+		}
+	}
+
+	client := redis.NewUniversalClient(opts)
+
+	err = client.Ping().Err()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis %v: %w", c.Addrs, err)
+	}
+
+	return &Redis{client, ttl, c.DB}, nil
+}
+
+func redisFromEnv(c RedisConfig) RedisConfig {
+
+	if addrs := config.Get("REDIS_ADDRS"); addrs != "" {
+		c.Addrs = strings.Split(addrs, ",")
+	} else if host := config.Get("REDIS_HOST"); host != "" {
+		c.Addrs = []string{fmt.Sprintf("%s:%s", host, config.Get("REDIS_PORT"))}
+	}
+
+	if len(c.Addrs) == 0 {
+		c.Addrs = []string{"localhost:6379"}
+	}
 
-	sTtl, _ := strconv.Atoi(ttl)
+	if v := config.Get("REDIS_MASTER_NAME"); v != "" {
+		c.MasterName = v
+	}
+	if v := config.Get("REDIS_USERNAME"); v != "" {
+		c.Username = v
+	}
+	if v := config.Get("REDIS_PASSWORD"); v != "" {
+		c.Password = v
+	}
+	if v := config.Get("REDIS_DB"); v != "" {
+		c.DB, _ = strconv.Atoi(v)
+	}
+	if v := config.Get("REDIS_TLS"); v != "" {
+		c.TLS.Enabled, _ = strconv.ParseBool(v)
+	}
+	if v := config.Get("REDIS_TLS_CA_FILE"); v != "" {
+		c.TLS.CAFile = v
+	}
 
-	return &Redis{c, sTtl}
+	return c
+}
+
+// nodes calls fn with every master of a cluster, or with the client itself.
+func (r *Redis) nodes(fn func(c redis.UniversalClient) error) error {
+	if cc, ok := r.client.(*redis.ClusterClient); ok {
+		return cc.ForEachMaster(func(c *redis.Client) error {
+			return fn(c)
+		})
+	}
+	return fn(r.client)
 }
 
 func (r *Redis) GetSession(id string) (*Session, error) {
@@ -61,12 +164,156 @@ func (r *Redis) Save(s *Session) error {
 		log.Println("error converting session to json", err)
 	}
 
-	err = r.client.Set(generateKey(s.GetID()), sJson, time.Second*time.Duration(r.ttl)).Err()
-	return err
+	ttl := time.Second * time.Duration(r.ttl)
+
+	err = r.client.Set(generateKey(s.GetID()), sJson, ttl).Err()
+	if err != nil || r.ttl == 0 {
+		return err
+	}
+
+	// the session key holds nothing once it expires, keep a copy that outlives
+	// it so expiry listeners can still see the last state
+	return r.client.Set(generateLastKey(s.GetID()), sJson, 2*ttl).Err()
 }
 
 func (r *Redis) Delete(id string) {
+	// separate commands as the keys may live in different cluster slots
 	r.client.Del(generateKey(id))
+	r.client.Del(generateLastKey(id))
+}
+
+func (r *Redis) List() ([]*Session, error) {
+
+	var sessions []*Session
+	var mu sync.Mutex
+
+	err := r.nodes(func(c redis.UniversalClient) error {
+
+		it := c.Scan(0, keyPrefix+"*", 100).Iterator()
+		for it.Next() {
+			key := it.Val()
+			if strings.HasSuffix(key, lastSuffix) {
+				continue
+			}
+
+			j, err := c.Get(key).Result()
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			var sess Session
+			err = FromJson(j, &sess)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			sessions = append(sessions, &sess)
+			mu.Unlock()
+		}
+
+		return it.Err()
+	})
+
+	return sessions, err
+}
+
+// OnExpire subscribes to keyspace notifications for expired session keys on
+// every node and calls l with the last saved state. Every instance receives
+// the notification, but only the one that removes the saved copy notifies
+// its listener.
+func (r *Redis) OnExpire(l ExpiryListener) {
+
+	err := r.nodes(func(c redis.UniversalClient) error {
+
+		enableExpiredEvents(c)
+
+		ps := c.PSubscribe(fmt.Sprintf("__keyevent@%d__:expired", r.db))
+
+		go func() {
+			for msg := range ps.Channel() {
+				if !strings.HasPrefix(msg.Payload, keyPrefix) || strings.HasSuffix(msg.Payload, lastSuffix) {
+					continue
+				}
+
+				s := r.claimExpired(strings.TrimPrefix(msg.Payload, keyPrefix))
+				if s != nil {
+					l(s)
+				}
+			}
+		}()
+
+		return nil
+	})
+
+	if err != nil {
+		utils.Logger.Error("could not subscribe to expired sessions", "error", err)
+	}
+}
+
+// enableExpiredEvents adds the flags for expired key events to the
+// notify-keyspace-events setting of the node, keeping those already set.
+func enableExpiredEvents(c redis.UniversalClient) {
+
+	v, err := c.ConfigGet("notify-keyspace-events").Result()
+	if err != nil || len(v) < 2 {
+		utils.Logger.Warn("could not read keyspace notifications, enable \"Ex\" on the server", "error", err)
+		return
+	}
+
+	current, _ := v[1].(string)
+	events := expiredEvents(current)
+	if events == current {
+		return
+	}
+
+	err = c.ConfigSet("notify-keyspace-events", events).Err()
+	if err != nil {
+		utils.Logger.Warn("could not enable keyspace notifications, enable \"Ex\" on the server", "error", err)
+	}
+}
+
+// expiredEvents returns the notify-keyspace-events flags with keyevent
+// notifications of expired keys added, "A" standing for all the classes.
+func expiredEvents(flags string) string {
+
+	if !strings.Contains(flags, "E") {
+		flags = flags + "E"
+	}
+	if !strings.ContainsAny(flags, "xA") {
+		flags = flags + "x"
+	}
+	return flags
+}
+
+func (r *Redis) claimExpired(id string) *Session {
+
+	key := generateLastKey(id)
+
+	j, err := r.client.Get(key).Result()
+	if err != nil {
+		if err != redis.Nil {
+			utils.Logger.Error("could not read expired session", "sessionId", id, "error", err)
+		}
+		return nil
+	}
+
+	n, err := r.client.Del(key).Result()
+	if err != nil || n == 0 {
+		return nil
+	}
+
+	var sess Session
+	err = FromJson(j, &sess)
+	if err != nil {
+		utils.Logger.Error("could not parse expired session", "sessionId", id, "error", err)
+		return nil
+	}
+
+	return &sess
 }
 
 func ToJson(sess *Session) (string, error) {
@@ -81,7 +328,11 @@ func FromJson(j string, sess *Session) error {
 }
 
 func generateKey(id string) string {
-	return fmt.Sprintf("sessions::%s", id)
+	return keyPrefix + id
+}
+
+func generateLastKey(id string) string {
+	return generateKey(id) + lastSuffix
 }
 
 func FromJsonArray(j string, sess *[]Session) error {
@@ -0,0 +1,24 @@
+package session
+
+import "testing"
+
+func TestExpiredEvents(t *testing.T) {
+
+	tests := []struct {
+		flags string
+		want  string
+	}{
+		{flags: "", want: "Ex"},
+		{flags: "Ex", want: "Ex"},
+		{flags: "KEA", want: "KEA"},
+		{flags: "Kg", want: "KgEx"},
+		{flags: "Kx", want: "KxE"},
+		{flags: "E$", want: "E$x"},
+	}
+
+	for _, tt := range tests {
+		if got := expiredEvents(tt.flags); got != tt.want {
+			t.Errorf("expiredEvents(%q) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ type Repository interface {
 	GetSession(id string) (*Session, error)
 	Save(s *Session) error
 	Delete(id string)
+	List() ([]*Session, error)
 }
 
 type FiberRepository interface {
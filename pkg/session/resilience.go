@@ -0,0 +1,365 @@
+package session
+
+import (
+	"errors"
+	"github.com/jamesdube/ussd/internal/utils"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("session repository circuit breaker is open")
+
+type RetryConfig struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+}
+
+type BreakerConfig struct {
+	Failures int
+	OpenFor  time.Duration `yaml:"openFor"`
+}
+
+type FallbackConfig struct {
+	Enabled   bool
+	Reconcile time.Duration
+}
+
+// ResilienceConfig describes the decorators wrapped around a repository.
+type ResilienceConfig struct {
+	Retry    RetryConfig
+	Breaker  BreakerConfig
+	Fallback FallbackConfig
+}
+
+// Resilient wraps r with retries, a circuit breaker and an in-memory
+// fallback, in that order, skipping those that are not configured.
+func Resilient(r Repository, c ResilienceConfig) Repository {
+
+	if c.Retry.Attempts > 1 {
+		r = NewRetry(r, c.Retry)
+	}
+
+	if c.Breaker.Failures > 0 {
+		r = NewCircuitBreaker(r, c.Breaker)
+	}
+
+	if c.Fallback.Enabled {
+		r = NewFallback(r, NewInMemory(), c.Fallback.Reconcile)
+	}
+
+	return r
+}
+
+func forwardExpiry(r Repository, l ExpiryListener) {
+	if e, ok := r.(Expirer); ok {
+		e.OnExpire(l)
+	}
+}
+
+// Retry retries failed reads and writes with exponential backoff.
+type Retry struct {
+	Repository
+	config RetryConfig
+}
+
+func NewRetry(r Repository, c RetryConfig) *Retry {
+	if c.Backoff == 0 {
+		c.Backoff = 50 * time.Millisecond
+	}
+	return &Retry{Repository: r, config: c}
+}
+
+func (r *Retry) GetSession(id string) (*Session, error) {
+	var s *Session
+	err := r.do("get", func() (err error) {
+		s, err = r.Repository.GetSession(id)
+		return err
+	})
+	return s, err
+}
+
+func (r *Retry) Save(s *Session) error {
+	return r.do("save", func() error {
+		return r.Repository.Save(s)
+	})
+}
+
+func (r *Retry) List() ([]*Session, error) {
+	var ss []*Session
+	err := r.do("list", func() (err error) {
+		ss, err = r.Repository.List()
+		return err
+	})
+	return ss, err
+}
+
+func (r *Retry) OnExpire(l ExpiryListener) {
+	forwardExpiry(r.Repository, l)
+}
+
+func (r *Retry) do(op string, fn func() error) error {
+
+	backoff := r.config.Backoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.config.Attempts {
+			return err
+		}
+
+		utils.Logger.Warn("session repository operation failed, retrying", "operation", op, "attempt", attempt, "error", err)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if r.config.MaxBackoff > 0 && backoff > r.config.MaxBackoff {
+			backoff = r.config.MaxBackoff
+		}
+	}
+}
+
+type breakerState int
+
+const (
+	closed breakerState = iota
+	open
+	halfOpen
+)
+
+// CircuitBreaker stops calling the repository after consecutive failures and
+// lets a single probe through once OpenFor has elapsed.
+type CircuitBreaker struct {
+	Repository
+	config   BreakerConfig
+	state    breakerState
+	failures int
+	openedAt time.Time
+	mu       sync.Mutex
+}
+
+func NewCircuitBreaker(r Repository, c BreakerConfig) *CircuitBreaker {
+	if c.OpenFor == 0 {
+		c.OpenFor = 10 * time.Second
+	}
+	return &CircuitBreaker{Repository: r, config: c}
+}
+
+func (b *CircuitBreaker) GetSession(id string) (*Session, error) {
+	if !b.allow() {
+		return nil, ErrCircuitOpen
+	}
+	s, err := b.Repository.GetSession(id)
+	b.done(err)
+	return s, err
+}
+
+func (b *CircuitBreaker) Save(s *Session) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := b.Repository.Save(s)
+	b.done(err)
+	return err
+}
+
+// Delete reports no outcome, so it only goes through while the breaker is
+// closed and never takes the probe of a half open one.
+func (b *CircuitBreaker) Delete(id string) {
+	if !b.isClosed() {
+		return
+	}
+	b.Repository.Delete(id)
+}
+
+func (b *CircuitBreaker) List() ([]*Session, error) {
+	if !b.allow() {
+		return nil, ErrCircuitOpen
+	}
+	ss, err := b.Repository.List()
+	b.done(err)
+	return ss, err
+}
+
+func (b *CircuitBreaker) OnExpire(l ExpiryListener) {
+	forwardExpiry(b.Repository, l)
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case open:
+		if time.Since(b.openedAt) < b.config.OpenFor {
+			return false
+		}
+		b.state = halfOpen
+		return true
+	case halfOpen:
+		return false
+	}
+	return true
+}
+
+func (b *CircuitBreaker) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == closed
+}
+
+func (b *CircuitBreaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if b.state != closed {
+			utils.Logger.Info("session repository circuit breaker closed")
+		}
+		b.state = closed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == halfOpen || b.failures >= b.config.Failures {
+		if b.state != open {
+			utils.Logger.Warn("session repository circuit breaker opened", "failures", b.failures, "error", err)
+		}
+		b.state = open
+		b.openedAt = time.Now()
+	}
+}
+
+// Fallback keeps sessions in a secondary repository while the primary is
+// failing and moves them back once the primary accepts writes again.
+type Fallback struct {
+	primary  Repository
+	fallback Repository
+	degraded map[string]bool
+	mu       sync.Mutex
+}
+
+func NewFallback(primary Repository, fallback Repository, reconcile time.Duration) *Fallback {
+
+	f := &Fallback{
+		primary:  primary,
+		fallback: fallback,
+		degraded: map[string]bool{},
+	}
+
+	if reconcile > 0 {
+		go func() {
+			for range time.Tick(reconcile) {
+				f.reconcile()
+			}
+		}()
+	}
+
+	return f
+}
+
+func (f *Fallback) GetSession(id string) (*Session, error) {
+
+	if f.isDegraded(id) {
+		return f.fallback.GetSession(id)
+	}
+
+	s, err := f.primary.GetSession(id)
+	if err != nil {
+		utils.Logger.Warn("session repository degraded, reading from fallback", "sessionId", id, "error", err)
+		return f.fallback.GetSession(id)
+	}
+	return s, nil
+}
+
+func (f *Fallback) Save(s *Session) error {
+
+	err := f.primary.Save(s)
+	if err == nil {
+		if f.isDegraded(s.GetID()) {
+			f.restore(s.GetID())
+		}
+		return nil
+	}
+
+	utils.Logger.Warn("session repository degraded, writing to fallback", "sessionId", s.GetID(), "error", err)
+
+	f.mu.Lock()
+	f.degraded[s.GetID()] = true
+	f.mu.Unlock()
+
+	return f.fallback.Save(s)
+}
+
+func (f *Fallback) Delete(id string) {
+	f.primary.Delete(id)
+	f.restore(id)
+}
+
+func (f *Fallback) List() ([]*Session, error) {
+
+	ss, err := f.primary.List()
+	if err != nil {
+		utils.Logger.Warn("session repository degraded, listing fallback only", "error", err)
+		ss = nil
+	}
+
+	seen := map[string]bool{}
+	var merged []*Session
+	degraded, _ := f.fallback.List()
+	for _, s := range degraded {
+		seen[s.GetID()] = true
+		merged = append(merged, s)
+	}
+	for _, s := range ss {
+		if !seen[s.GetID()] {
+			merged = append(merged, s)
+		}
+	}
+
+	return merged, nil
+}
+
+func (f *Fallback) OnExpire(l ExpiryListener) {
+	forwardExpiry(f.primary, l)
+	forwardExpiry(f.fallback, l)
+}
+
+func (f *Fallback) isDegraded(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.degraded[id]
+}
+
+func (f *Fallback) restore(id string) {
+	f.mu.Lock()
+	delete(f.degraded, id)
+	f.mu.Unlock()
+	f.fallback.Delete(id)
+}
+
+// reconcile copies sessions written during degradation back to the primary.
+func (f *Fallback) reconcile() {
+
+	f.mu.Lock()
+	var ids []string
+	for id := range f.degraded {
+		ids = append(ids, id)
+	}
+	f.mu.Unlock()
+
+	for _, id := range ids {
+		s, err := f.fallback.GetSession(id)
+		if err != nil {
+			continue
+		}
+
+		err = f.primary.Save(s)
+		if err != nil {
+			return
+		}
+
+		utils.Logger.Info("reconciled session to primary repository", "sessionId", id)
+		f.restore(id)
+	}
+}
@@ -0,0 +1,304 @@
+package session
+
+import (
+	"errors"
+	"github.com/jamesdube/ussd/internal/utils"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+var errDown = errors.New("repository down")
+
+// fakeRepository fails its next failures calls, or every call while down.
+type fakeRepository struct {
+	sessions map[string]*Session
+	failures int
+	down     bool
+	calls    int
+	deletes  int
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{sessions: map[string]*Session{}}
+}
+
+func (r *fakeRepository) fail() error {
+	r.calls++
+	if r.down {
+		return errDown
+	}
+	if r.failures > 0 {
+		r.failures--
+		return errDown
+	}
+	return nil
+}
+
+func (r *fakeRepository) GetSession(id string) (*Session, error) {
+	if err := r.fail(); err != nil {
+		return nil, err
+	}
+	if s, ok := r.sessions[id]; ok {
+		return s, nil
+	}
+	return NewSession(id), nil
+}
+
+func (r *fakeRepository) Save(s *Session) error {
+	if err := r.fail(); err != nil {
+		return err
+	}
+	r.sessions[s.GetID()] = s
+	return nil
+}
+
+func (r *fakeRepository) Delete(id string) {
+	r.deletes++
+	delete(r.sessions, id)
+}
+
+func (r *fakeRepository) List() ([]*Session, error) {
+	if err := r.fail(); err != nil {
+		return nil, err
+	}
+	var ss []*Session
+	for _, s := range r.sessions {
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+func TestRetry(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		attempts  int
+		failures  int
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", attempts: 3, wantCalls: 1},
+		{name: "recovers", attempts: 3, failures: 2, wantCalls: 3},
+		{name: "gives up", attempts: 3, failures: 5, wantCalls: 3, wantErr: errDown},
+		{name: "single attempt", attempts: 1, failures: 1, wantCalls: 1, wantErr: errDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			repo.failures = tt.failures
+			r := NewRetry(repo, RetryConfig{Attempts: tt.attempts, Backoff: time.Microsecond, MaxBackoff: time.Microsecond})
+
+			err := r.Save(NewSession("s1"))
+			if err != tt.wantErr {
+				t.Errorf("Save() error = %v, want %v", err, tt.wantErr)
+			}
+			if repo.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", repo.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// step is an operation on a circuit breaker, with the repository failing it
+// when fail is set.
+type step struct {
+	op      string
+	fail    bool
+	wantErr error
+}
+
+func TestCircuitBreaker(t *testing.T) {
+
+	const openFor = 5 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		steps       []step
+		wantState   breakerState
+		wantDeletes int
+	}{
+		{
+			name:      "stays closed below the threshold",
+			steps:     []step{{op: "save", fail: true, wantErr: errDown}, {op: "save"}, {op: "save", fail: true, wantErr: errDown}, {op: "save"}},
+			wantState: closed,
+		},
+		{
+			name:      "opens after consecutive failures",
+			steps:     []step{{op: "save", fail: true, wantErr: errDown}, {op: "save", fail: true, wantErr: errDown}, {op: "save", wantErr: ErrCircuitOpen}},
+			wantState: open,
+		},
+		{
+			name: "probe closes",
+			steps: []step{
+				{op: "save", fail: true, wantErr: errDown}, {op: "save", fail: true, wantErr: errDown},
+				{op: "wait"}, {op: "get"}, {op: "save"},
+			},
+			wantState: closed,
+		},
+		{
+			name: "probe reopens",
+			steps: []step{
+				{op: "save", fail: true, wantErr: errDown}, {op: "save", fail: true, wantErr: errDown},
+				{op: "wait"}, {op: "get", fail: true, wantErr: errDown}, {op: "save", wantErr: ErrCircuitOpen},
+			},
+			wantState: open,
+		},
+		{
+			name: "delete only while closed",
+			steps: []step{
+				{op: "delete"}, {op: "save", fail: true, wantErr: errDown}, {op: "save", fail: true, wantErr: errDown},
+				{op: "delete"}, {op: "wait"}, {op: "delete"}, {op: "save"}, {op: "delete"},
+			},
+			wantState:   closed,
+			wantDeletes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			b := NewCircuitBreaker(repo, BreakerConfig{Failures: 2, OpenFor: openFor})
+
+			for i, s := range tt.steps {
+				repo.down = s.fail
+
+				var err error
+				switch s.op {
+				case "get":
+					_, err = b.GetSession("s1")
+				case "save":
+					err = b.Save(NewSession("s1"))
+				case "delete":
+					b.Delete("s1")
+				case "wait":
+					time.Sleep(2 * openFor)
+				}
+
+				if err != s.wantErr {
+					t.Fatalf("step %d %s: error = %v, want %v", i, s.op, err, s.wantErr)
+				}
+			}
+
+			if b.state != tt.wantState {
+				t.Errorf("state = %d, want %d", b.state, tt.wantState)
+			}
+			if repo.deletes != tt.wantDeletes {
+				t.Errorf("deletes = %d, want %d", repo.deletes, tt.wantDeletes)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+
+	repo := newFakeRepository()
+	repo.down = true
+	b := NewCircuitBreaker(repo, BreakerConfig{Failures: 1, OpenFor: time.Millisecond})
+
+	b.Save(NewSession("s1"))
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("first call after OpenFor was not let through")
+	}
+	if b.allow() {
+		t.Error("second call was let through while the probe is pending")
+	}
+}
+
+func TestFallback(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		recover       bool
+		reconcile     bool
+		saveAgain     bool
+		wantPrimary   bool
+		wantDegraded  bool
+		wantFallbacks int
+	}{
+		{name: "degraded", wantDegraded: true, wantFallbacks: 1},
+		{name: "reconcile while down", reconcile: true, wantDegraded: true, wantFallbacks: 1},
+		{name: "reconcile once recovered", recover: true, reconcile: true, wantPrimary: true},
+		{name: "save once recovered", recover: true, saveAgain: true, wantPrimary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newFakeRepository()
+			secondary := newFakeRepository()
+			f := NewFallback(primary, secondary, 0)
+
+			s := NewSession("s1")
+			s.Msisdn = "263771234567"
+
+			primary.down = true
+			if err := f.Save(s); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			got, err := f.GetSession("s1")
+			if err != nil || got.Msisdn != s.Msisdn {
+				t.Fatalf("GetSession() = %v, %v, want the degraded session", got, err)
+			}
+
+			primary.down = !tt.recover
+			if tt.reconcile {
+				f.reconcile()
+			}
+			if tt.saveAgain {
+				f.Save(s)
+			}
+
+			if _, ok := primary.sessions["s1"]; ok != tt.wantPrimary {
+				t.Errorf("in primary = %v, want %v", ok, tt.wantPrimary)
+			}
+			if d := f.isDegraded("s1"); d != tt.wantDegraded {
+				t.Errorf("degraded = %v, want %v", d, tt.wantDegraded)
+			}
+			if n := len(secondary.sessions); n != tt.wantFallbacks {
+				t.Errorf("sessions in fallback = %d, want %d", n, tt.wantFallbacks)
+			}
+		})
+	}
+}
+
+func TestFallbackList(t *testing.T) {
+
+	primary := newFakeRepository()
+	secondary := newFakeRepository()
+	f := NewFallback(primary, secondary, 0)
+
+	f.Save(&Session{Id: "s1", Msisdn: "old"})
+	primary.down = true
+	f.Save(&Session{Id: "s1", Msisdn: "new"})
+	f.Save(&Session{Id: "s2"})
+
+	ss, err := f.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(ss) != 2 {
+		t.Fatalf("len(List()) = %d, want 2", len(ss))
+	}
+
+	primary.down = false
+	ss, _ = f.List()
+	for _, s := range ss {
+		if s.Id == "s1" && s.Msisdn != "new" {
+			t.Errorf("s1 listed from the primary, want the fallback copy")
+		}
+	}
+	if len(ss) != 2 {
+		t.Errorf("len(List()) = %d, want 2", len(ss))
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 type Session struct {
 	Id               string            `json:"id"`
+	Msisdn           string            `json:"msisdn"`
 	Attributes       map[string]string `json:"attributes"`
 	Selections       []string          `json:"selections"`
 	Active           bool              `json:"active"`
@@ -13,6 +14,19 @@ type Session struct {
 	PaginatedHasMore bool              `json:"PaginatedHasMore"`
 	Pages            [][]string        `json:"pages"`
 	CurrentPage      int               `json:"currentPage"`
+	OptionPages      [][]Option        `json:"optionPages"`
+	Texts            []string          `json:"texts"`
+	Closing          bool              `json:"closing"`
+	Keys             map[string]string `json:"keys"`
+	Secrets          map[string]bool   `json:"secrets,omitempty"`
+	Stack            []Frame           `json:"stack"`
+}
+
+// Frame is the navigation state saved before moving to another menu, the
+// pages of a paginated menu are rebuilt by rendering it again.
+type Frame struct {
+	Selections  []string `json:"selections"`
+	CurrentPage int      `json:"currentPage"`
 }
 
 func NewSession(id string) *Session {
@@ -40,3 +54,62 @@ func (s *Session) GetSelections() []string {
 func (s *Session) GetID() string {
 	return s.Id
 }
+
+// Public returns the attributes without those marked secret, for anything
+// leaving the framework such as the admin API and abandoned events.
+func (s *Session) Public() map[string]string {
+	attrs := make(map[string]string, len(s.Attributes))
+	for k, v := range s.Attributes {
+		if !s.Secrets[k] {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+// maxStack bounds the stack of sessions looping through transitions.
+const maxStack = 32
+
+// Push saves the current navigation state on the stack. Nothing is saved
+// before the first selection, there is no menu to go back to.
+func (s *Session) Push() {
+	if len(s.Selections) == 0 {
+		return
+	}
+	if len(s.Stack) >= maxStack {
+		s.Stack = s.Stack[1:]
+	}
+
+	var page int
+	if s.Paginated {
+		page = s.CurrentPage
+	}
+
+	s.Stack = append(s.Stack, Frame{
+		Selections:  append([]string(nil), s.Selections...),
+		CurrentPage: page,
+	})
+}
+
+// Pop restores the navigation state saved last and drops the pages of the
+// current menu, it returns false when the stack is empty.
+func (s *Session) Pop() bool {
+
+	if len(s.Stack) == 0 {
+		return false
+	}
+
+	f := s.Stack[len(s.Stack)-1]
+	s.Stack = s.Stack[:len(s.Stack)-1]
+
+	s.Selections = f.Selections
+	s.CurrentPage = f.CurrentPage
+	s.Paginated = false
+	s.PaginatedHasMore = false
+	s.Pages = nil
+	s.OptionPages = nil
+	s.Texts = nil
+	s.Closing = false
+
+	return true
+}
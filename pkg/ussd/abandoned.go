@@ -0,0 +1,41 @@
+package ussd
+
+import (
+	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/session"
+)
+
+// AbandonedEvent describes a session that expired in the store without being
+// ended by a menu.
+type AbandonedEvent struct {
+	SessionId  string
+	Msisdn     string
+	Route      string
+	Selections []string
+	Attributes map[string]string
+}
+
+type AbandonedHandler func(e AbandonedEvent)
+
+func (f *Framework) onSessionExpired(s *session.Session) {
+
+	var route string
+	if rt := f.routes().Match(s.GetSelections()); rt != nil {
+		route = rt.Key
+	}
+
+	e := AbandonedEvent{
+		SessionId:  s.GetID(),
+		Msisdn:     s.Msisdn,
+		Route:      route,
+		Selections: s.GetSelections(),
+		Attributes: s.Public(),
+	}
+
+	utils.Logger.Debug("session abandoned", "sessionId", e.SessionId, "route", e.Route)
+	abandonedSessions.WithLabelValues(e.Route).Inc()
+
+	for _, h := range f.abandonedHandlers {
+		h(e)
+	}
+}
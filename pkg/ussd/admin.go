@@ -0,0 +1,176 @@
+package ussd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	cfg "github.com/jamesdube/ussd/internal/config"
+	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/session"
+)
+
+const defaultAdminPrefix = "/admin"
+
+// SetupAdmin exposes the session admin API when enabled in config.yaml. With
+// a port configured it is served by a separate app, otherwise it is mounted
+// on app under the configured prefix. Both require the admin token.
+func SetupAdmin(framework *Framework, app *fiber.App) {
+
+	ac := framework.config.Admin
+	if !ac.Enabled {
+		return
+	}
+
+	token := ac.Token
+	if token == "" {
+		token = cfg.Get("ADMIN_TOKEN")
+	}
+
+	prefix := ac.Prefix
+	if prefix == "" {
+		prefix = defaultAdminPrefix
+	}
+
+	if token == "" {
+		utils.Logger.Warn("admin api requires a token, not serving it")
+		return
+	}
+
+	if ac.Port == 0 {
+		adminRoutes(framework, app.Group(prefix, authorize(token)))
+		utils.Logger.Debug("mounted admin api", "prefix", prefix)
+		return
+	}
+
+	admin := fiber.New(fiber.Config{DisableStartupMessage: true})
+	admin.Use(authorize(token))
+	adminRoutes(framework, admin.Group(prefix))
+
+	go func() {
+		utils.Logger.Error(admin.Listen(fmt.Sprintf(":%d", ac.Port)).Error())
+	}()
+}
+
+func adminRoutes(framework *Framework, r fiber.Router) {
+	r.Get("/sessions", listSessions(framework))
+	r.Get("/sessions/:id", getSession(framework))
+	r.Delete("/sessions/:id", terminateSession(framework))
+	r.Get("/msisdn/:msisdn", getSessionsByMsisdn(framework))
+	r.Delete("/msisdn/:msisdn/journey", eraseJourney(framework))
+}
+
+func authorize(token string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		given := []byte(ctx.Get(fiber.HeaderAuthorization))
+		if subtle.ConstantTimeCompare(given, []byte("Bearer "+token)) != 1 {
+			return ctx.SendStatus(fiber.StatusUnauthorized)
+		}
+		return ctx.Next()
+	}
+}
+
+func listSessions(framework *Framework) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		ss, err := framework.ListSessions()
+		if err != nil {
+			utils.Logger.Error("failed to list sessions", "error", err)
+			return ctx.SendStatus(fiber.StatusServiceUnavailable)
+		}
+
+		return ctx.JSON(public(ss...))
+	}
+}
+
+func getSession(framework *Framework) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		ss, err := framework.GetSession(ctx.Params("id"))
+		if err != nil {
+			utils.Logger.Error("failed to get session", "error", err)
+			return ctx.SendStatus(fiber.StatusServiceUnavailable)
+		}
+
+		// repositories return a new session for unknown ids, stored ones
+		// always have a subscriber
+		if ss == nil || ss.Msisdn == "" {
+			return ctx.SendStatus(fiber.StatusNotFound)
+		}
+
+		return ctx.JSON(public(ss)[0])
+	}
+}
+
+func getSessionsByMsisdn(framework *Framework) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		ss, err := findSessions(framework, func(s *session.Session) bool {
+			return s.Msisdn == ctx.Params("msisdn")
+		})
+		if err != nil {
+			return ctx.SendStatus(fiber.StatusServiceUnavailable)
+		}
+
+		if len(ss) == 0 {
+			return ctx.SendStatus(fiber.StatusNotFound)
+		}
+
+		return ctx.JSON(public(ss...))
+	}
+}
+
+func terminateSession(framework *Framework) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		id := ctx.Params("id")
+		utils.Logger.Info("terminating session from admin api", "sessionId", id)
+		framework.DeleteSession(id)
+
+		return ctx.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+func eraseJourney(framework *Framework) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		if framework.journey == nil {
+			return ctx.SendStatus(fiber.StatusNotFound)
+		}
+
+		err := framework.journey.Erase(ctx.Params("msisdn"))
+		if err != nil {
+			utils.Logger.Error("failed to erase journey", "error", err)
+			return ctx.SendStatus(fiber.StatusServiceUnavailable)
+		}
+
+		return ctx.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+func findSessions(framework *Framework, match func(s *session.Session) bool) ([]*session.Session, error) {
+
+	all, err := framework.ListSessions()
+	if err != nil {
+		utils.Logger.Error("failed to list sessions", "error", err)
+		return nil, err
+	}
+
+	ss := []*session.Session{}
+	for _, s := range all {
+		if match(s) {
+			ss = append(ss, s)
+		}
+	}
+	return ss, nil
+}
+
+// public copies the sessions without their secret attributes.
+func public(ss ...*session.Session) []*session.Session {
+	out := make([]*session.Session, 0, len(ss))
+	for _, s := range ss {
+		p := *s
+		p.Attributes = s.Public()
+		out = append(out, &p)
+	}
+	return out
+}
@@ -5,6 +5,8 @@ import (
 	cfg "github.com/jamesdube/ussd/internal/config"
 	"github.com/jamesdube/ussd/internal/utils"
 	"github.com/jamesdube/ussd/pkg/gateway"
+	"github.com/jamesdube/ussd/pkg/i18n"
+	"github.com/jamesdube/ussd/pkg/journey"
 	"github.com/jamesdube/ussd/pkg/menu"
 	"github.com/jamesdube/ussd/pkg/middleware"
 	"github.com/jamesdube/ussd/pkg/router"
@@ -13,51 +15,88 @@ import (
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"log/slog"
+	"sync/atomic"
 )
 
 type Framework struct {
-	router             *router.Router
+	tree               atomic.Value
 	registry           *gateway.Registry
 	sessions           map[string]session.Session
 	sessionRepository  session.Repository
 	menuRegistry       *menu.Registry
 	config             *config
 	middlewareRegistry middleware.Registry
+	abandonedHandlers  []AbandonedHandler
+	journey            *journey.Recorder
+	mounts             []mount
+	guards             []guardEntry
+	messages           *i18n.Bundle
+	language           i18n.Resolver
 }
 
+const configFile = "config.yaml"
+
 type config struct {
 	App struct {
 		Port int
 	}
 
 	Cluster struct {
-		Provider string
+		Provider   string
+		Redis      session.RedisConfig
+		Hazelcast  session.HazelcastConfig
+		Resilience map[string]session.ResilienceConfig
 	}
 
 	Menu struct {
-		Navigation map[string]string
+		Navigation  map[string]string
+		Definitions map[string]*menu.Definition
+		Files       []string
+		Strict      bool
+		Watch       bool
+		Keys        struct {
+			Back string
+			Home string
+		}
+		Pagination struct {
+			More     string
+			Previous string
+			Limit    int
+		}
+	}
+
+	I18n struct {
+		Dir       string
+		Fallback  string
+		Attribute string
+	}
+
+	Admin struct {
+		Enabled bool
+		Port    int
+		Prefix  string
+		Token   string
 	}
 }
 
 func Init(logger *slog.Logger) *Framework {
 
 	utils.SetLogger(logger)
-	configFile, err := ioutil.ReadFile("config.yaml")
+	b, err := ioutil.ReadFile(configFile)
 
 	var c config
 	if err != nil {
 		utils.Logger.Warn("could not find config file")
 	} else {
-		err2 := yaml.Unmarshal(configFile, &c)
+		err2 := yaml.Unmarshal(b, &c)
 		if err2 != nil {
 			utils.Logger.Warn("could not parse config file")
 		}
 	}
 
-	sr := getRepository()
+	sr := getRepository(&c)
 
 	f := &Framework{
-		router:            router.NewRouter(),
 		registry:          &gateway.Registry{},
 		sessions:          map[string]session.Session{},
 		menuRegistry:      menu.NewRegistry(),
@@ -65,7 +104,13 @@ func Init(logger *slog.Logger) *Framework {
 		config:            &c,
 	}
 
+	f.tree.Store(&tree{router: router.NewRouter(), menus: f.menuRegistry, config: &c})
 	f.setup()
+	f.setupMessages()
+
+	if e, ok := sr.(session.Expirer); ok {
+		e.OnExpire(f.onSessionExpired)
+	}
 
 	return f
 }
@@ -101,6 +146,18 @@ func (f *Framework) GetOrCreateSession(id string) (*session.Session, error) {
 
 }
 
+func (f *Framework) ListSessions() ([]*session.Session, error) {
+	utils.Logger.Debug("listing sessions from repository")
+	ss, err := f.sessionRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	if ss == nil {
+		ss = []*session.Session{}
+	}
+	return ss, nil
+}
+
 func (f *Framework) RemoveLastSessionEntry(id string) {
 	ss, _ := f.GetSession(id)
 	ss.RemoveLastSelection()
@@ -121,13 +178,9 @@ func (f *Framework) SaveSession(s *session.Session) {
 
 }
 
+// AddMenu routes the key to a registered menu in the active route table.
 func (f *Framework) AddMenu(k string, m string) {
-	mn := f.menuRegistry.Find(m)
-
-	if mn != nil {
-		f.router.AddRoute(k, mn)
-		utils.Logger.Debug("registered route", "routeKey", k, "routeMenu", m)
-	}
+	f.current().addRoute(k, m)
 }
 
 func (f *Framework) setup() {
@@ -135,23 +188,54 @@ func (f *Framework) setup() {
 	f.registry.Register(e)
 }
 
-func (f *Framework) configureMenus() {
-	for k, v := range f.config.Menu.Navigation {
-		f.AddMenu(k, v)
+// setupMessages loads the catalogs of i18n.dir and resolves the locale from
+// the session attribute i18n.attribute, "language" by default.
+func (f *Framework) setupMessages() {
+
+	c := f.config.I18n
+
+	attr := c.Attribute
+	if attr == "" {
+		attr = "language"
+	}
+	f.language = i18n.Attribute(attr)
+
+	if c.Dir == "" {
+		return
 	}
+
+	fallback := c.Fallback
+	if fallback == "" {
+		fallback = "en"
+	}
+
+	b := i18n.NewBundle(fallback)
+	err := b.LoadDir(c.Dir)
+	if err != nil {
+		panic(fmt.Errorf("fatal error loading message catalogs: %w", err))
+	}
+	f.messages = b
 }
 
-func getRepository() session.Repository {
+func getRepository(c *config) session.Repository {
 
 	p := cfg.Get("SESSION_PROVIDER")
 
 	switch p {
 	case "redis":
 		logProvider("redis")
-		return session.NewRedis()
+		r, err := session.NewRedis(c.Cluster.Redis)
+		if err != nil {
+			panic(fmt.Errorf("fatal error connecting to redis: %w", err))
+		}
+		return session.Resilient(r, c.Cluster.Resilience["redis"])
 	case "hazelcast":
 		logProvider("hazelcast")
-		return session.NewHazelCast("ussd")
+		h, err := session.NewHazelCast(c.Cluster.Hazelcast)
+		if err != nil {
+			panic(fmt.Errorf("fatal error connecting to hazelcast: %w", err))
+		}
+		return session.Resilient(h, c.Cluster.Resilience["hazelcast"])
 	default:
 		logProvider("memory")
 		return session.NewInMemory()
@@ -0,0 +1,82 @@
+package ussd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	u "github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/gateway"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/middleware"
+	"github.com/jamesdube/ussd/pkg/session"
+)
+
+const maxRedirects = 8
+
+// guard runs the guards attached to the route the session moved to and
+// returns the menu to render. A redirect moves the session to the named menu
+// and the guards of that route run in turn.
+func guard(f *Framework, ctx *fiber.Ctx, c *menu.Context, ss *session.Session, msg string, mn menu.Menu) (menu.Menu, error) {
+
+	for i := 0; i < maxRedirects; i++ {
+
+		target := ""
+		for _, g := range f.table(ctx).Guards(ss.GetSelections()) {
+			d := g.Check(c, msg)
+
+			if d.Action == middleware.Block {
+				return nil, &middleware.BlockedError{Message: d.Message}
+			}
+
+			for k, v := range d.Attributes {
+				if ss.Attributes == nil {
+					ss.Attributes = map[string]string{}
+					c.Context = ss.Attributes
+				}
+				c.Add(k, v)
+			}
+
+			if d.Action == middleware.Redirect {
+				target = d.Menu
+				break
+			}
+		}
+
+		if target == "" {
+			return mn, nil
+		}
+
+		path, err := f.table(ctx).PathTo(target, ss.GetSelections())
+		if err != nil {
+			return nil, err
+		}
+
+		u.Logger.Debug("guard redirect", "menu", target, "route", path)
+		ss.Selections = path
+		ss.Paginated = false
+		c.Paginated = false
+		f.SaveSession(ss)
+
+		mn = f.routeTo(ctx, c, ss.GetSelections())
+		if mn == nil {
+			return nil, fmt.Errorf("menu not found for guard redirect to %s", target)
+		}
+	}
+
+	return nil, fmt.Errorf("more than %d guard redirects", maxRedirects)
+}
+
+// onGuardError ends the session with the message of a blocking guard, other
+// errors end it as an invalid selection.
+func onGuardError(err error, f *Framework, ctx *fiber.Ctx, g gateway.Gateway, ss *session.Session, msisdn string) error {
+
+	var b *middleware.BlockedError
+	if !errors.As(err, &b) || b.Message == "" {
+		return onErrorWith(err.Error(), f, ctx, g, ss, msisdn)
+	}
+
+	u.Logger.Info("request blocked by guard", "route", ss.GetSelections())
+	f.DeleteSession(ss.Id)
+	r := buildResponse(ctx, g, b.Message, nil, ss, msisdn, false)
+	return sendResponse(r, ctx)
+}
@@ -0,0 +1,25 @@
+package ussd
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/jamesdube/ussd/pkg/i18n"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/session"
+)
+
+// localize gives the menu context the catalogs and the subscriber's locale.
+func (f *Framework) localize(c *menu.Context, ss *session.Session) {
+	c.Messages = f.messages
+	if f.language != nil {
+		c.Locale = f.language(ss.Msisdn, ss.Attributes)
+	}
+}
+
+// translate returns a framework message in the locale of the current
+// request, or in English before the request has a menu context.
+func translate(ctx *fiber.Ctx, key string) string {
+	if h := currentHop(ctx); h != nil && h.context != nil {
+		return h.context.T(key)
+	}
+	return i18n.Default().T("", key)
+}
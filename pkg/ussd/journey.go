@@ -0,0 +1,98 @@
+package ussd
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/jamesdube/ussd/pkg/gateway"
+	"github.com/jamesdube/ussd/pkg/journey"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/router"
+	"time"
+)
+
+const hopKey = "ussd.hop"
+
+// hop collects what happens during a request so it can be queued for the
+// journey trail once the request has been handled.
+type hop struct {
+	journey.Hop
+	start   time.Time
+	context *menu.Context
+}
+
+func startHop(ctx *fiber.Ctx, gr gateway.Request) *hop {
+	h := &hop{
+		Hop: journey.Hop{
+			SessionId: gr.SessionId,
+			Msisdn:    gr.Msisdn,
+			Message:   gr.Message,
+		},
+		start: time.Now(),
+	}
+	ctx.Locals(hopKey, h)
+	return h
+}
+
+func currentHop(ctx *fiber.Ctx) *hop {
+	h, _ := ctx.Locals(hopKey).(*hop)
+	return h
+}
+
+func traceContext(ctx *fiber.Ctx, c *menu.Context) {
+	if h := currentHop(ctx); h != nil {
+		h.context = c
+	}
+}
+
+func traceRoute(ctx *fiber.Ctx, rt *router.Route) {
+	if h := currentHop(ctx); h != nil && rt != nil {
+		h.Route = rt.Key
+		h.Menu = rt.Name
+	}
+}
+
+// traceInput records the route of the menu receiving the message, empty when
+// the message dials the service, and whether the message is secret.
+func traceInput(ctx *fiber.Ctx, m menu.Menu) {
+	if h := currentHop(ctx); h != nil {
+		h.InputRoute = h.Route
+		h.Secret = secretInput(m)
+	}
+}
+
+func traceResponse(ctx *fiber.Ctx, prompt string, active bool) {
+	if h := currentHop(ctx); h != nil {
+		h.Prompt = prompt
+		h.Active = active
+	}
+}
+
+// routeTo resolves the menu for the selections, exposes the captured route
+// params on the menu context and records the route on the current hop.
+func (f *Framework) routeTo(ctx *fiber.Ctx, c *menu.Context, s []string) menu.Menu {
+	rt, params := f.table(ctx).Resolve(s)
+	if rt == nil {
+		return nil
+	}
+	c.Params = params
+	traceRoute(ctx, rt)
+	return rt.Menu
+}
+
+func (f *Framework) recordHop(h *hop) {
+
+	if h.Route != "" {
+		routeRequests.WithLabelValues(h.Route).Inc()
+	}
+
+	if f.journey == nil {
+		return
+	}
+
+	h.Time = h.start
+	h.Latency = time.Since(h.start)
+	if h.context != nil {
+		h.Navigation = h.context.NavigationType.String()
+	}
+
+	f.journey.Record(h.Hop)
+}
@@ -0,0 +1,31 @@
+package ussd
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var abandonedSessions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ussd_sessions_abandoned_total",
+	Help: "Sessions that expired without being ended, by the route they were last on.",
+}, []string{"route"})
+
+var routeRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ussd_route_requests_total",
+	Help: "Requests answered by each route.",
+}, []string{"route"})
+
+var experimentAssignments = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ussd_experiment_assignments_total",
+	Help: "Sessions assigned to each variant of an experiment.",
+}, []string{"experiment", "variant"})
+
+var configVersion = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "ussd_config_version",
+	Help: "Time the active menu configuration was loaded, labelled with its checksum.",
+}, []string{"checksum"})
+
+var configReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ussd_config_reloads_total",
+	Help: "Menu configuration reloads, by result.",
+}, []string{"result"})
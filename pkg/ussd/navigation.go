@@ -0,0 +1,93 @@
+package ussd
+
+import (
+	"github.com/gofiber/fiber/v2"
+	u "github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/gateway"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/session"
+	"strings"
+)
+
+// globalNavigation returns the navigation requested through the reserved
+// back and home keys, or Continue when msg is an ordinary input for the
+// current menu.
+func globalNavigation(f *Framework, current menu.Menu, ss *session.Session, msg string) menu.NavigationType {
+
+	if o, ok := current.(menu.NavigationOptOut); ok && o.DisableGlobalNavigation() {
+		return menu.Continue
+	}
+
+	keys := f.config.Menu.Keys
+	switch {
+	case keys.Home != "" && msg == keys.Home:
+		return menu.Home
+	case keys.Back != "" && msg == keys.Back:
+		return menu.Back
+	}
+
+	return menu.Continue
+}
+
+// goBack restores the navigation state saved before the current menu and
+// renders it again.
+func goBack(f *Framework, c *menu.Context, ctx *fiber.Ctx, gw gateway.Gateway, ss *session.Session, msisdn string) error {
+
+	if !ss.Pop() && len(ss.Selections) > 1 {
+		ss.RemoveLastSelection()
+		ss.Paginated = false
+		ss.CurrentPage = 0
+	}
+
+	return renderCurrent(f, c, ctx, gw, ss, msisdn)
+}
+
+// goHome returns to the menu of the dialled short code.
+func goHome(f *Framework, c *menu.Context, ctx *fiber.Ctx, gw gateway.Gateway, ss *session.Session, msisdn string) error {
+
+	if len(ss.Selections) > 1 {
+		ss.Selections = ss.Selections[:1]
+	}
+	ss.Stack = nil
+	ss.Paginated = false
+	ss.CurrentPage = 0
+
+	return renderCurrent(f, c, ctx, gw, ss, msisdn)
+}
+
+// renderCurrent renders the menu routed from the session selections again,
+// showing the page the subscriber was on when it is paginated. The guards of
+// the route run as they do when moving forward.
+func renderCurrent(f *Framework, c *menu.Context, ctx *fiber.Ctx, gw gateway.Gateway, ss *session.Session, msisdn string) error {
+
+	page := ss.CurrentPage - 1
+	if page < 0 {
+		page = 0
+	}
+
+	c.Paginated = false
+	c.NavigationType = menu.Continue
+
+	mn := f.routeTo(ctx, c, ss.GetSelections())
+	if mn == nil {
+		u.Logger.Error("menu not found for route", "route", ss.GetSelections())
+		return onErrorWith(u.MenuInvalidSelection, f, ctx, gw, ss, msisdn)
+	}
+
+	var msg string
+	if len(ss.Selections) > 0 {
+		msg = ss.Selections[len(ss.Selections)-1]
+	}
+
+	route := strings.Join(ss.Selections, ".")
+	mn, err := guard(f, ctx, c, ss, msg, mn)
+	if err != nil {
+		return onGuardError(err, f, ctx, gw, ss, msisdn)
+	}
+	if strings.Join(ss.Selections, ".") != route {
+		page = 0
+	}
+
+	res := mn.OnRequest(c, msg)
+	return renderPage(f, c, ctx, gw, ss, msisdn, res, page)
+}
@@ -0,0 +1,84 @@
+package ussd
+
+import (
+	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/session"
+	"strconv"
+	"strings"
+)
+
+// items returns the options of a response, options given as strings get
+// their position as id.
+func items(r menu.Response) []menu.Option {
+
+	if len(r.Items) > 0 {
+		return r.Items
+	}
+
+	var options []menu.Option
+	for i, o := range r.Options {
+		options = append(options, menu.Option{Id: strconv.Itoa(i + 1), Label: o})
+	}
+	return options
+}
+
+// present returns the options of the response and remembers their keys on
+// the session, so the next request can be mapped to an option id.
+func present(ss *session.Session, r menu.Response) []menu.Option {
+	options := items(r)
+	ss.Keys = keys(options)
+	return options
+}
+
+// keys maps the key of every option to its id. Options without a key are
+// numbered in the order they are shown, disabled ones included so keys do
+// not move when an option is switched off, their keys map to an empty id.
+func keys(options []menu.Option) map[string]string {
+
+	if len(options) == 0 {
+		return nil
+	}
+
+	k := map[string]string{}
+	n := 0
+	for _, o := range options {
+		key := o.Key
+		if key == "" && !o.Hidden {
+			n++
+			key = strconv.Itoa(n)
+		}
+		switch {
+		case key == "":
+			continue
+		case o.Disabled:
+			k[key] = ""
+		default:
+			k[key] = o.Id
+		}
+	}
+	return k
+}
+
+// disabled reports whether msg selects a disabled option.
+func disabled(ss *session.Session, msg string) bool {
+	id, ok := ss.Keys[msg]
+	return ok && id == ""
+}
+
+func buildOptions(options []menu.Option) string {
+
+	var sb strings.Builder
+	n := 0
+	for _, o := range options {
+		if o.Hidden {
+			continue
+		}
+		key := o.Key
+		if key == "" {
+			n++
+			key = strconv.Itoa(n)
+		}
+		sb.WriteString("\n" + key + ". " + o.Label)
+	}
+	return sb.String()
+}
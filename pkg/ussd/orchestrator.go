@@ -88,8 +88,8 @@ func build(req Request, mr menu.Response, sess *session.Session) Response {
 
 	m := mr.Prompt
 
-	if len(mr.Options) > 0 {
-		opt := buildOptions(mr.Options)
+	if options := items(mr); len(options) > 0 {
+		opt := buildOptions(options)
 		m = m + opt
 	}
 
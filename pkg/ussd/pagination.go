@@ -0,0 +1,378 @@
+package ussd
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	u "github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/gateway"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/session"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// pager splits screens in pages fitting the character limit of the gateway,
+// a limit of 0 leaves screens whole. The more and previous keys move between
+// the pages.
+type pager struct {
+	limit         int
+	more          string
+	previous      string
+	moreLabel     string
+	previousLabel string
+	selectPrompt  string
+}
+
+// paging returns a pager with the keys of menu.pagination, "0" for the next
+// page and "#" for the previous one by default.
+func (f *Framework) paging() pager {
+
+	c := f.config.Menu.Pagination
+
+	p := pager{limit: c.Limit, more: c.More, previous: c.Previous}
+	if p.more == "" {
+		p.more = "0"
+	}
+	if p.previous == "" {
+		p.previous = "#"
+	}
+	return p
+}
+
+// checkKeys returns an error when the paging keys and the back and home keys
+// overlap, the input would be ambiguous.
+func (f *Framework) checkKeys() error {
+
+	p := f.paging()
+	keys := f.config.Menu.Keys
+
+	seen := map[string]string{}
+	for _, k := range []struct{ name, key string }{
+		{"menu.pagination.more", p.more},
+		{"menu.pagination.previous", p.previous},
+		{"menu.keys.back", keys.Back},
+		{"menu.keys.home", keys.Home},
+	} {
+		if k.key == "" {
+			continue
+		}
+		if other, ok := seen[k.key]; ok {
+			return fmt.Errorf("%s and %s are both %q", other, k.name, k.key)
+		}
+		seen[k.key] = k.name
+	}
+	return nil
+}
+
+// pager returns the pager of the current request, labelled in the locale of
+// the subscriber. menu.pagination.limit overrides the limit of the gateway.
+func (f *Framework) pager(ctx *fiber.Ctx, gw gateway.Gateway) pager {
+
+	p := f.paging()
+	p.moreLabel = translate(ctx, "menu.more")
+	p.previousLabel = translate(ctx, "menu.previous")
+	p.selectPrompt = translate(ctx, "menu.select_option")
+
+	if l, ok := gw.(gateway.Limiter); ok && p.limit == 0 {
+		p.limit = l.Limit()
+	}
+	return p
+}
+
+// turn returns the index of the page msg moves to, current is the number of
+// the page shown and total the number of pages.
+func (p pager) turn(current int, total int, msg string) (int, bool) {
+
+	switch {
+	case msg == p.more && current < total:
+		return current, true
+	case msg == p.previous && current > 1 && current <= total:
+		return current - 2, true
+	}
+	return 0, false
+}
+
+// navigation returns the lines offering the next and the previous page.
+func (p pager) navigation(previous bool, more bool) string {
+
+	var sb strings.Builder
+	if more {
+		sb.WriteString("\n" + p.more + ". " + p.moreLabel)
+	}
+	if previous {
+		sb.WriteString("\n" + p.previous + ". " + p.previousLabel)
+	}
+	return sb.String()
+}
+
+func (p pager) fits(s string) bool {
+	return p.limit <= 0 || utf8.RuneCountInString(s) <= p.limit
+}
+
+// overflows reports whether the response does not fit on one screen.
+func (p pager) overflows(r menu.Response) bool {
+	return !p.fits(r.Prompt + buildOptions(items(r)))
+}
+
+// paginate splits options in pages of at most perPage options, each page
+// holding as many as fit the limit along with its prompt and navigation.
+// Every page holds at least one option.
+func (p pager) paginate(prompt string, options []menu.Option, perPage int) [][]menu.Option {
+
+	var pages [][]menu.Option
+	for i := 0; i < len(options); {
+
+		header := p.selectPrompt
+		if i == 0 {
+			header = prompt
+		}
+
+		rest := options[i:]
+		max := len(rest)
+		if perPage > 0 && perPage < max {
+			max = perPage
+		}
+
+		// the last page drops the more line, so a longer page may fit
+		// where a shorter one does not
+		n := 1
+		for k := 2; k <= max; k++ {
+			if p.fits(header + buildOptions(rest[:k]) + p.navigation(i > 0, k < len(rest))) {
+				n = k
+			}
+		}
+
+		pages = append(pages, rest[:n])
+		i = i + n
+	}
+
+	if len(pages) == 0 {
+		pages = [][]menu.Option{nil}
+	}
+	return pages
+}
+
+// split breaks text in chunks fitting the limit with both navigation lines,
+// cutting at the last line break, else the last space, of every chunk.
+func (p pager) split(text string) []string {
+
+	budget := p.limit - utf8.RuneCountInString(p.navigation(true, true))
+	if p.limit <= 0 || budget <= 0 {
+		return []string{text}
+	}
+
+	var chunks []string
+	r := []rune(text)
+	for len(r) > budget {
+
+		cut := lastIndex(r[:budget+1], '\n')
+		if cut <= 0 {
+			cut = lastIndex(r[:budget+1], ' ')
+		}
+		if cut <= 0 {
+			cut = budget
+		}
+
+		chunks = append(chunks, strings.TrimRight(string(r[:cut]), " \n"))
+		r = []rune(strings.TrimLeft(string(r[cut:]), " \n"))
+	}
+
+	return append(chunks, string(r))
+}
+
+func lastIndex(r []rune, c rune) int {
+	for i := len(r) - 1; i >= 0; i-- {
+		if r[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// offset returns the number of options shown on the pages before the current
+// one.
+func offset(pages [][]string, current int) int {
+
+	n := 0
+	for i := 0; i < current-1 && i < len(pages); i++ {
+		n = n + len(pages[i])
+	}
+	return n
+}
+
+// createPagination splits the response in pages. The options of paginated
+// responses are split in pages of PerPage, hidden options can be selected
+// from every page. The prompt of other responses is split in chunks fitting
+// the limit, the options follow the last chunk.
+func createPagination(c *menu.Context, menuResponse menu.Response, session *session.Session, p pager) {
+
+	var shown, hidden []menu.Option
+	for _, o := range items(menuResponse) {
+		if o.Hidden {
+			hidden = append(hidden, o)
+		} else {
+			shown = append(shown, o)
+		}
+	}
+
+	var texts []string
+	var optionPages [][]menu.Option
+
+	if menuResponse.Paginated {
+		for i, page := range p.paginate(menuResponse.Prompt, shown, menuResponse.PerPage) {
+			var text string
+			if i == 0 {
+				text = menuResponse.Prompt
+			}
+			texts = append(texts, text)
+			optionPages = append(optionPages, append(append([]menu.Option(nil), page...), hidden...))
+		}
+	} else {
+		options := items(menuResponse)
+		texts = p.split(menuResponse.Prompt)
+		last := texts[len(texts)-1]
+		if len(options) > 0 && !p.fits(last+buildOptions(options)+p.navigation(len(texts) > 1, false)) {
+			texts = append(texts, "")
+		}
+		optionPages = make([][]menu.Option, len(texts))
+		optionPages[len(texts)-1] = options
+	}
+
+	var pages [][]string
+	for _, page := range optionPages {
+		var labels []string
+		for _, o := range page {
+			if !o.Hidden {
+				labels = append(labels, o.Label)
+			}
+		}
+		pages = append(pages, labels)
+	}
+
+	c.Pages = pages
+	c.CurrentPage = 0
+	session.Paginated = true
+	session.PaginatedHasMore = len(c.Pages) > 1
+	session.Pages = c.Pages
+	session.OptionPages = optionPages
+	session.Texts = texts
+	session.CurrentPage = c.CurrentPage
+
+}
+
+// render shows the response of a menu, in pages when it is paginated or does
+// not fit the screen. Sessions stopped by the response end on its last page.
+func render(f *Framework, c *menu.Context, ctx *fiber.Ctx, gw gateway.Gateway, ss *session.Session, msisdn string, r menu.Response) error {
+	return renderPage(f, c, ctx, gw, ss, msisdn, r, 0)
+}
+
+// renderPage renders the response like render, starting on the page at
+// index, or the last page when it has fewer.
+func renderPage(f *Framework, c *menu.Context, ctx *fiber.Ctx, gw gateway.Gateway, ss *session.Session, msisdn string, r menu.Response, index int) error {
+
+	p := f.pager(ctx, gw)
+	ss.Closing = false
+
+	if r.Paginated || p.overflows(r) {
+
+		createPagination(c, r, ss, p)
+
+		if r.NavigationType == menu.Stop {
+			ss.Closing = true
+		} else {
+			postNavigation(f, c, ss, r)
+		}
+
+		if index >= len(ss.OptionPages) {
+			index = len(ss.OptionPages) - 1
+		}
+		return showPage(f, c, ctx, gw, ss, msisdn, index, "")
+	}
+
+	ss.Paginated = false
+	c.Paginated = false
+	options := present(ss, r)
+	postNavigation(f, c, ss, r)
+
+	res := buildResponse(ctx, gw, r.Prompt, options, ss, msisdn, c.Active)
+	return sendResponse(res, ctx)
+}
+
+// showPage renders the page at index, preceded by notice when it is set.
+func showPage(f *Framework, c *menu.Context, ctx *fiber.Ctx, gw gateway.Gateway, ss *session.Session, msisdn string, index int, notice string) error {
+
+	if index < 0 || index >= len(ss.OptionPages) {
+		return onErrorWith(u.MenuNoMoreOptions, f, ctx, gw, ss, msisdn)
+	}
+
+	p := f.pager(ctx, gw)
+	options := ss.OptionPages[index]
+
+	text := p.selectPrompt
+	if index < len(ss.Texts) && ss.Texts[index] != "" {
+		text = ss.Texts[index]
+	}
+	if notice != "" {
+		text = notice + "\n" + text
+	}
+
+	ss.CurrentPage = index + 1
+	ss.PaginatedHasMore = ss.CurrentPage < len(ss.OptionPages)
+	ss.Keys = keys(options)
+	c.CurrentPage = ss.CurrentPage
+
+	if ss.Closing && !ss.PaginatedHasMore {
+		f.DeleteSession(ss.Id)
+		c.Active = false
+	} else {
+		f.SaveSession(ss)
+	}
+
+	m := text + buildOptions(options)
+	if c.Active {
+		m = m + p.navigation(index > 0, ss.PaginatedHasMore)
+	}
+
+	r := buildResponse(ctx, gw, m, nil, ss, msisdn, c.Active)
+	return sendResponse(r, ctx)
+}
+
+// handlePagination moves between the pages of the current menu, any other
+// input selects an option of the page shown. Pages without options but
+// the last reject it.
+func handlePagination(framework *Framework, c *menu.Context, ctx *fiber.Ctx, message string, msisdn string, gateway gateway.Gateway, session *session.Session) error {
+
+	p := framework.pager(ctx, gateway)
+
+	if page, ok := p.turn(session.CurrentPage, len(session.OptionPages), message); ok {
+		return showPage(framework, c, ctx, gateway, session, msisdn, page, "")
+	}
+
+	if _, ok := session.Keys[message]; !ok && message == p.more {
+		u.Logger.Debug("no more pages", "route", session.GetSelections())
+		return showPage(framework, c, ctx, gateway, session, msisdn, session.CurrentPage-1, translate(ctx, "menu.no_more_options"))
+	}
+
+	// the last page of a response without options takes free input, as
+	// the response would on one screen
+	last := session.CurrentPage >= len(session.OptionPages)
+	if (len(session.Keys) > 0 || !last) && session.Keys[message] == "" {
+		postNavigation(framework, c, session, menu.Response{NavigationType: menu.Continue})
+		u.Logger.Error("invalid pagination option", "route", session.GetSelections())
+		return onErrorWith(u.MenuInvalidSelection, framework, ctx, gateway, session, msisdn)
+	}
+
+	io, _ := strconv.Atoi(message)
+	c.SelectedPaginationOption = io + offset(session.Pages, session.CurrentPage)
+	c.SelectedPageOption = io
+	c.Selected = session.Keys[message]
+	prev := framework.routeTo(ctx, c, session.GetSelections())
+	if prev == nil {
+		u.Logger.Error("menu not found for route", "route", session.GetSelections())
+		return onErrorWith(u.MenuInvalidSelection, framework, ctx, gateway, session, msisdn)
+	}
+	prev.Process(c, message)
+
+	return proceed(framework, c, ctx, gateway, session, prev, message, msisdn)
+}
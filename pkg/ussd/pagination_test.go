@@ -0,0 +1,231 @@
+package ussd
+
+import (
+	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	utils.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+func testPager(limit int) pager {
+	return pager{
+		limit:         limit,
+		more:          "0",
+		previous:      "#",
+		moreLabel:     "More",
+		previousLabel: "Previous",
+		selectPrompt:  "Select",
+	}
+}
+
+func options(labels ...string) []menu.Option {
+	var o []menu.Option
+	for _, l := range labels {
+		o = append(o, menu.Option{Id: l, Label: l})
+	}
+	return o
+}
+
+func TestTurn(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		current int
+		total   int
+		msg     string
+		want    int
+		ok      bool
+	}{
+		{name: "more", current: 1, total: 3, msg: "0", want: 1, ok: true},
+		{name: "more on the last page", current: 3, total: 3, msg: "0"},
+		{name: "previous", current: 2, total: 3, msg: "#", want: 0, ok: true},
+		{name: "previous on the first page", current: 1, total: 3, msg: "#"},
+		{name: "previous past the last page", current: 4, total: 3, msg: "#"},
+		{name: "option", current: 2, total: 3, msg: "1"},
+	}
+
+	p := testPager(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := p.turn(tt.current, tt.total, tt.msg)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("turn(%d, %d, %q) = %d, %v, want %d, %v", tt.current, tt.total, tt.msg, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		limit   int
+		perPage int
+		options []menu.Option
+		want    []int
+	}{
+		{name: "per page", perPage: 2, options: options("A", "B", "C", "D", "E"), want: []int{2, 2, 1}},
+		{name: "no per page", options: options("A", "B", "C", "D", "E"), want: []int{5}},
+		{name: "empty", perPage: 2, want: []int{0}},
+		{name: "limit", limit: 28, options: options("A", "B", "C", "D", "E"), want: []int{3, 2}},
+		{name: "last page drops more", limit: 30, options: options("A", "B", "C", "D", "E"), want: []int{5}},
+		{name: "limit and per page", limit: 30, perPage: 2, options: options("A", "B", "C", "D", "E"), want: []int{2, 1, 2}},
+		{name: "one option at least", limit: 5, options: options("A", "B", "C"), want: []int{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := testPager(tt.limit).paginate("Pick", tt.options, tt.perPage)
+
+			var got []int
+			for _, page := range pages {
+				got = append(got, len(page))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("page sizes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		limit int
+		text  string
+		want  []string
+	}{
+		{name: "no limit", text: "aaaa bbbb cccc", want: []string{"aaaa bbbb cccc"}},
+		{name: "limit below navigation", limit: 20, text: "aaaa bbbb cccc", want: []string{"aaaa bbbb cccc"}},
+		{name: "fits", limit: 30, text: "short", want: []string{"short"}},
+		{name: "space", limit: 30, text: "aaaa bbbb cccc", want: []string{"aaaa bbbb", "cccc"}},
+		{name: "line break", limit: 30, text: "aa\nbbbb cccc", want: []string{"aa", "bbbb cccc"}},
+		{name: "hard cut", limit: 30, text: "abcdefghijklmno", want: []string{"abcdefghij", "klmno"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testPager(tt.limit).split(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOffset(t *testing.T) {
+
+	pages := [][]string{{"a", "b"}, {"c"}, {"d"}}
+
+	tests := []struct {
+		current int
+		want    int
+	}{
+		{current: 0, want: 0},
+		{current: 1, want: 0},
+		{current: 2, want: 2},
+		{current: 3, want: 3},
+		{current: 5, want: 4},
+	}
+
+	for _, tt := range tests {
+		if got := offset(pages, tt.current); got != tt.want {
+			t.Errorf("offset(%d) = %d, want %d", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		options []menu.Option
+		want    map[string]string
+	}{
+		{name: "empty"},
+		{
+			name:    "numbered",
+			options: options("a", "b"),
+			want:    map[string]string{"1": "a", "2": "b"},
+		},
+		{
+			name: "custom key",
+			options: []menu.Option{
+				{Id: "a", Label: "a"},
+				{Id: "help", Label: "Help", Key: "*"},
+				{Id: "b", Label: "b"},
+			},
+			want: map[string]string{"1": "a", "*": "help", "2": "b"},
+		},
+		{
+			name: "hidden",
+			options: []menu.Option{
+				{Id: "a", Label: "a"},
+				{Id: "agent", Label: "Agent", Key: "88", Hidden: true},
+				{Id: "secret", Label: "Secret", Hidden: true},
+				{Id: "b", Label: "b"},
+			},
+			want: map[string]string{"1": "a", "88": "agent", "2": "b"},
+		},
+		{
+			name: "disabled",
+			options: []menu.Option{
+				{Id: "a", Label: "a", Disabled: true},
+				{Id: "b", Label: "b"},
+			},
+			want: map[string]string{"1": "", "2": "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keys(tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		more     string
+		previous string
+		back     string
+		home     string
+		wantErr  bool
+	}{
+		{name: "defaults"},
+		{name: "back and home", back: "99", home: "00"},
+		{name: "back on the more default", back: "0", wantErr: true},
+		{name: "home on the previous default", home: "#", wantErr: true},
+		{name: "more and previous", more: "9", previous: "9", wantErr: true},
+		{name: "back and home alike", back: "00", home: "00", wantErr: true},
+		{name: "custom paging keys", more: "98", previous: "97", back: "0", home: "00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config{}
+			c.Menu.Pagination.More = tt.more
+			c.Menu.Pagination.Previous = tt.previous
+			c.Menu.Keys.Back = tt.back
+			c.Menu.Keys.Home = tt.home
+
+			err := (&Framework{config: c}).checkKeys()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkKeys() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -5,7 +5,6 @@ import (
 	u "github.com/jamesdube/ussd/internal/utils"
 	"github.com/jamesdube/ussd/pkg/menu"
 	"github.com/jamesdube/ussd/pkg/session"
-	"go.uber.org/zap"
 )
 
 type Normal struct {
@@ -18,11 +17,11 @@ func (n *Normal) Render(message string, ctx *menu.Context, sess *session.Session
 	}
 
 	sess.AddSelection(message)
-	mn := f.router.RouteTo(sess.GetSelections())
+	mn := f.routes().RouteTo(sess.GetSelections())
 
 	if mn == nil {
 
-		u.Logger.Error("menu not found for route", zap.Any("route", sess.GetSelections()))
+		u.Logger.Error("menu not found for route", "route", sess.GetSelections())
 		return menu.Response{
 			Prompt: "error",
 		}
@@ -34,7 +33,7 @@ func (n *Normal) Render(message string, ctx *menu.Context, sess *session.Session
 		sess.Paginated = true
 		fmt.Println("create pagination")
 
-		createPagination(ctx, r, sess)
+		createPagination(ctx, r, sess, f.paging())
 	}
 
 	f.SaveSession(sess)
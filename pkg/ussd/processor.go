@@ -7,9 +7,8 @@ import (
 	u "github.com/jamesdube/ussd/internal/utils"
 	"github.com/jamesdube/ussd/pkg/gateway"
 	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/middleware"
 	"github.com/jamesdube/ussd/pkg/session"
-	"go.uber.org/zap"
-	"strconv"
 	"strings"
 )
 
@@ -26,77 +25,120 @@ func process(framework *Framework, name string) func(ctx *fiber.Ctx) error {
 			return ctx.SendString("failed to unmarshal")
 		}
 
+		defer framework.recordHop(startHop(ctx, gr))
+		framework.pin(ctx)
+
 		msg := gr.Message
 
 		ss, e := framework.GetOrCreateSession(gr.SessionId)
 
 		if e != nil {
-			u.Logger.Error("failed to initiate session")
-			return onError(framework, ctx, gw, ss, gr.Msisdn)
+			u.Logger.Error("failed to initiate session", "sessionId", gr.SessionId, "error", e)
+			return onError(framework, ctx, gw, session.NewSession(gr.SessionId), gr.Msisdn)
 		}
 
-		err = runMiddleware(framework, ss, gr)
+		ss.Msisdn = gr.Msisdn
+
+		err = runMiddleware(framework.middlewareRegistry.Get(), ss, gr)
+		if err == nil {
+			err = runMiddleware(framework.table(ctx).Middleware(ss.GetSelections(), msg), ss, gr)
+		}
 		if err != nil {
 			return onErrorWith(err.Error(), framework, ctx, gw, ss, gr.Msisdn)
 		}
 
 		c := menu.NewContext(gr.Msisdn, ss)
+		framework.localize(c, ss)
+		traceContext(ctx, c)
+
+		prev := framework.routeTo(ctx, c, ss.GetSelections())
+		traceInput(ctx, prev)
+
+		switch globalNavigation(framework, prev, ss, msg) {
+		case menu.Back:
+			return goBack(framework, c, ctx, gw, ss, gr.Msisdn)
+		case menu.Home:
+			return goHome(framework, c, ctx, gw, ss, gr.Msisdn)
+		}
 
 		if c.Paginated {
-			return handlePagination(framework, c, ctx, gr.Message, "Please select an option:", gr.Msisdn, gw, ss)
+			return handlePagination(framework, c, ctx, gr.Message, gr.Msisdn, gw, ss)
 		}
 
-		prev := framework.router.RouteTo(ss.GetSelections())
+		if disabled(ss, msg) {
+			return onErrorWith(u.MenuInvalidSelection, framework, ctx, gw, ss, gr.Msisdn)
+		}
 
 		if prev != nil {
+			c.Selected = ss.Keys[msg]
 			prev.Process(c, msg)
 		}
 
-		if c.NavigationType == menu.Replay {
-
-			fmt.Println("replay wanted")
-			pr := prev.OnRequest(c, msg)
-
-			postNavigation(framework, c, ss, pr)
-
-			r := buildResponse(gw, pr.Prompt, pr.Options, ss, gr.Msisdn, c.Active)
-			return sendResponse(r, ctx)
+		return proceed(framework, c, ctx, gw, ss, prev, msg, gr.Msisdn)
+	}
 
-		}
+}
 
-		ss.AddSelection(msg)
-		framework.SaveSession(ss)
-		mn := framework.router.RouteTo(ss.GetSelections())
+// proceed handles the navigation requested by the Process of the previous
+// menu and renders the menu the session moves to.
+func proceed(framework *Framework, c *menu.Context, ctx *fiber.Ctx, gw gateway.Gateway, ss *session.Session, prev menu.Menu, msg string, msisdn string) error {
 
-		if mn == nil {
-			u.Logger.Error("menu not found for route", "route", ss.GetSelections())
-			return onErrorWith(u.MenuInvalidSelection, framework, ctx, gw, ss, gr.Msisdn)
-		}
+	switch c.NavigationType {
+	case menu.Back:
+		return goBack(framework, c, ctx, gw, ss, msisdn)
+	case menu.Home:
+		return goHome(framework, c, ctx, gw, ss, msisdn)
+	}
 
-		rMsg := mn.OnRequest(c, msg)
+	if c.NavigationType == menu.Replay && c.Target == "" {
 
-		if rMsg.Paginated {
+		fmt.Println("replay wanted")
+		pr := prev.OnRequest(c, msg)
+		return render(framework, c, ctx, gw, ss, msisdn, pr)
 
-			createPagination(c, rMsg, ss)
+	}
 
-			postNavigation(framework, c, ss, rMsg)
+	// secret input goes no further than the menu asking for it
+	if secretInput(prev) {
+		msg = secretSelection
+	}
 
-			return handlePagination(framework, c, ctx, gr.Message, rMsg.Prompt, gr.Msisdn, gw, ss)
+	err := advance(framework, ctx, c, ss, msg)
+	if err != nil {
+		return onErrorWith(err.Error(), framework, ctx, gw, ss, msisdn)
+	}
+	ss.Paginated = false
+	c.Paginated = false
+	framework.SaveSession(ss)
+	mn := framework.routeTo(ctx, c, ss.GetSelections())
+
+	if mn == nil {
+		u.Logger.Error("menu not found for route", "route", ss.GetSelections())
+		return onErrorWith(u.MenuInvalidSelection, framework, ctx, gw, ss, msisdn)
+	}
 
-		}
+	mn, err = guard(framework, ctx, c, ss, msg, mn)
+	if err != nil {
+		return onGuardError(err, framework, ctx, gw, ss, msisdn)
+	}
 
-		postNavigation(framework, c, ss, rMsg)
+	rMsg := mn.OnRequest(c, msg)
+	return render(framework, c, ctx, gw, ss, msisdn, rMsg)
+}
 
-		r := buildResponse(gw, rMsg.Prompt, rMsg.Options, ss, gr.Msisdn, c.Active)
-		return sendResponse(r, ctx)
-	}
+// secretSelection is the selection kept in place of secret input.
+const secretSelection = "*"
 
+// secretInput reports whether the input of m is secret.
+func secretInput(m menu.Menu) bool {
+	s, ok := m.(menu.SecretInput)
+	return ok && s.SecretInput()
 }
 
 func onError(framework *Framework, ctx *fiber.Ctx, gateway gateway.Gateway, ss *session.Session, msisdn string) error {
 
 	framework.DeleteSession(ss.Id)
-	r := buildResponse(gateway, u.MenuInvalidSelection, nil, ss, msisdn, false)
+	r := buildResponse(ctx, gateway, translate(ctx, "menu.invalid_selection"), nil, ss, msisdn, false)
 	return sendResponse(r, ctx)
 
 }
@@ -105,7 +147,7 @@ func onErrorWith(msg string, framework *Framework, ctx *fiber.Ctx, gateway gatew
 
 	u.Logger.Error(msg)
 	framework.DeleteSession(ss.Id)
-	r := buildResponse(gateway, u.MenuInvalidSelection, nil, ss, msisdn, false)
+	r := buildResponse(ctx, gateway, translate(ctx, "menu.invalid_selection"), nil, ss, msisdn, false)
 	return sendResponse(r, ctx)
 
 }
@@ -133,8 +175,32 @@ func postNavigation(f *Framework, c *menu.Context, ss *session.Session, response
 	}
 }
 
-func runMiddleware(f *Framework, ss *session.Session, gr gateway.Request) error {
-	for _, m := range f.middlewareRegistry.Get() {
+// advance moves the session to the next menu, either the explicit target set
+// through menu.Context.Goto or the route of the message appended to the
+// selections, saving the current state for the back key.
+func advance(f *Framework, ctx *fiber.Ctx, c *menu.Context, ss *session.Session, msg string) error {
+
+	if c.Target == "" {
+		ss.Push()
+		ss.AddSelection(msg)
+		return nil
+	}
+
+	path, err := f.table(ctx).PathTo(c.Target, ss.GetSelections())
+	if err != nil {
+		return err
+	}
+
+	u.Logger.Debug("transition to menu", "menu", c.Target, "route", path)
+	ss.Push()
+	ss.Selections = path
+	c.Target = ""
+
+	return nil
+}
+
+func runMiddleware(mws []middleware.Middleware, ss *session.Session, gr gateway.Request) error {
+	for _, m := range mws {
 		errM := m.Handle(ss, &gr)
 		if errM != nil {
 			return errM
@@ -143,7 +209,7 @@ func runMiddleware(f *Framework, ss *session.Session, gr gateway.Request) error
 	return nil
 }
 
-func buildResponse(g gateway.Gateway, message string, options []string, session *session.Session, msisdn string, active bool) interface{} {
+func buildResponse(ctx *fiber.Ctx, g gateway.Gateway, message string, options []menu.Option, session *session.Session, msisdn string, active bool) interface{} {
 
 	m := message
 
@@ -152,9 +218,7 @@ func buildResponse(g gateway.Gateway, message string, options []string, session
 		m = message + opt
 	}
 
-	if session.PaginatedHasMore && session.Paginated {
-		m = m + "\n0. More"
-	}
+	traceResponse(ctx, m, active)
 
 	return g.ToResponse(gateway.Response{
 		Message:       m,
@@ -165,17 +229,6 @@ func buildResponse(g gateway.Gateway, message string, options []string, session
 
 }
 
-func buildOptions(options []string) string {
-
-	var sb strings.Builder
-	i := 1
-	for _, o := range options {
-		sb.WriteString("\n" + strconv.Itoa(i) + ". " + o)
-		i++
-	}
-	return sb.String()
-}
-
 func sendResponse(grs interface{}, ctx *fiber.Ctx) error {
 
 	result, _ := xml.Marshal(&grs)
@@ -184,111 +237,3 @@ func sendResponse(grs interface{}, ctx *fiber.Ctx) error {
 	ctx.Type("xml")
 	return ctx.Send([]byte(u.Header + xmls))
 }
-
-func handlePagination(framework *Framework, c *menu.Context, ctx *fiber.Ctx, message string, prompt string, msisdn string, gateway gateway.Gateway, session *session.Session) error {
-
-	first := session.CurrentPage == 0
-	cont := first || message == "0"
-	last := (len(c.Pages)) == (c.CurrentPage) || (len(c.Pages)) == 1
-
-	if len(c.Pages) == (c.CurrentPage + 1) {
-		session.PaginatedHasMore = false
-	}
-
-	if last && message == "0" {
-		return onErrorWith(u.MenuNoMoreOptions, framework, ctx, gateway, session, msisdn)
-	}
-
-	if !first && !cont || last {
-
-		io, e := strconv.Atoi(message)
-		validOption := isValidOption(c, io)
-		if e != nil || !validOption {
-			postNavigation(framework, c, session, menu.Response{NavigationType: menu.Continue})
-			u.Logger.Error("invalid pagination option", zap.Any("route", session.GetSelections()))
-			return onErrorWith(u.MenuInvalidSelection, framework, ctx, gateway, session, msisdn)
-		}
-
-		var optionsCount int
-		if (len(c.Pages)) > 1 && c.CurrentPage > 1 {
-			pagesViewed := c.CurrentPage - 1
-			for i := 0; i < pagesViewed; i++ {
-				optionsCount = optionsCount + len(c.Pages[i])
-			}
-		}
-
-		c.SelectedPaginationOption = io + optionsCount
-		c.SelectedPageOption = io
-		prev := framework.router.RouteTo(session.GetSelections())
-		prev.Process(c, message)
-
-		session.AddSelection(message)
-		mn := framework.router.RouteTo(session.GetSelections())
-
-		if mn == nil {
-
-			u.Logger.Error("menu not found for route", zap.Any("route", session.GetSelections()))
-			return onErrorWith(u.MenuInvalidSelection, framework, ctx, gateway, session, msisdn)
-		}
-
-		res := mn.OnRequest(c, message)
-
-		postNavigation(framework, c, session, res)
-
-		c.Paginated = false
-		session.Paginated = false
-
-		r := buildResponse(gateway, res.Prompt, res.Options, session, msisdn, c.Active)
-		return sendResponse(r, ctx)
-
-	}
-
-	if cont {
-		session.CurrentPage++
-		framework.SaveSession(session)
-	}
-
-	r := buildResponse(gateway, prompt, c.Pages[c.CurrentPage], session, msisdn, c.Active)
-
-	return sendResponse(r, ctx)
-
-}
-
-func isValidOption(ctx *menu.Context, io int) bool {
-
-	if ctx.CurrentPage == 0 {
-		return true
-	}
-
-	b := (io - 1) < len(ctx.Pages[ctx.CurrentPage-1])
-	return b
-}
-
-func createPagination(c *menu.Context, menuResponse menu.Response, session *session.Session) {
-
-	if menuResponse.PerPage == 0 {
-		menuResponse.PerPage = len(menuResponse.Options)
-	}
-
-	var pages [][]string
-	//var options []string
-	for i := 0; i < len(menuResponse.Options); i = i + menuResponse.PerPage {
-
-		r := (i + menuResponse.PerPage) < len(menuResponse.Options)
-		max := i + menuResponse.PerPage
-		if !r {
-			max = len(menuResponse.Options)
-		}
-
-		pages = append(pages, menuResponse.Options[i:max])
-
-	}
-
-	c.Pages = pages
-	c.CurrentPage = 0
-	session.Paginated = true
-	session.PaginatedHasMore = len(c.Pages) > 1
-	session.Pages = c.Pages
-	session.CurrentPage = c.CurrentPage
-
-}
@@ -0,0 +1,262 @@
+package ussd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/journey"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"io"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// screen is the message and stage of an Econet response.
+type screen struct {
+	Message string `xml:"message"`
+	Stage   string `xml:"stage"`
+}
+
+// prompt is a menu showing its name and options, paginated when perPage is
+// set, it moves on with the input.
+type prompt struct {
+	name    string
+	options []string
+	perPage int
+}
+
+func (m *prompt) OnRequest(c *menu.Context, msg string) menu.Response {
+	return menu.Response{Prompt: m.name, Options: m.options, Paginated: m.perPage > 0, PerPage: m.perPage}
+}
+
+func (m *prompt) Process(c *menu.Context, msg string) menu.NavigationType {
+	return menu.Continue
+}
+
+// echo is a menu showing the input it was reached with.
+type echo struct{}
+
+func (m *echo) OnRequest(c *menu.Context, msg string) menu.Response {
+	return menu.Response{Prompt: "You entered " + msg}
+}
+
+func (m *echo) Process(c *menu.Context, msg string) menu.NavigationType {
+	return menu.Continue
+}
+
+// newTestApp serves the menus with the configuration cfg, written as the
+// config.yaml of a temporary working directory.
+func newTestApp(t *testing.T, cfg string, menus map[string]menu.Menu) (*Framework, *fiber.App) {
+	t.Helper()
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err := os.WriteFile(configFile, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := Init(utils.Logger)
+	for name, m := range menus {
+		f.menuRegistry.Add(name, m)
+	}
+	f.configureMenus()
+
+	app := fiber.New()
+	SetupRoutes(f, app)
+	return f, app
+}
+
+// send posts msg for the session to the Econet gateway.
+func send(t *testing.T, app *fiber.App, sessionId string, msg string) screen {
+	t.Helper()
+
+	b, _ := json.Marshal(map[string]string{"sourceNumber": "263771234567", "message": msg, "transactionID": sessionId})
+	req := httptest.NewRequest("POST", "/econet", strings.NewReader(string(b)))
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+
+	var s screen
+	if err := xml.Unmarshal(body, &s); err != nil {
+		t.Fatalf("response %s: %v", body, err)
+	}
+	return s
+}
+
+func TestGlobalNavigation(t *testing.T) {
+
+	_, app := newTestApp(t, `
+menu:
+  keys:
+    back: "99"
+    home: "00"
+  navigation:
+    "*": main
+    "*.1": balance
+    "*.1.1": history
+`, map[string]menu.Menu{
+		"main":    &prompt{name: "Main", options: []string{"Balance"}},
+		"balance": &prompt{name: "Balance", options: []string{"History"}},
+		"history": &prompt{name: "History"},
+	})
+
+	tests := []struct {
+		name   string
+		inputs []string
+		want   string
+	}{
+		{name: "back on the main menu", inputs: []string{"*123#", "99"}, want: "Main"},
+		{name: "home on the main menu", inputs: []string{"*123#", "00"}, want: "Main"},
+		{name: "back twice to the main menu", inputs: []string{"*123#", "1", "1", "99", "99", "99"}, want: "Main"},
+		{name: "back", inputs: []string{"*123#", "1", "1", "99"}, want: "Balance"},
+		{name: "home", inputs: []string{"*123#", "1", "1", "00"}, want: "Main"},
+		{name: "forward after back", inputs: []string{"*123#", "99", "1"}, want: "Balance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s screen
+			for _, msg := range tt.inputs {
+				s = send(t, app, tt.name, msg)
+			}
+			if !strings.HasPrefix(s.Message, tt.want) {
+				t.Errorf("message = %q, want %q", s.Message, tt.want)
+			}
+			if s.Stage == "COMPLETE" {
+				t.Errorf("session ended")
+			}
+		})
+	}
+}
+
+func TestRouteRemovedWhilePaging(t *testing.T) {
+
+	f, app := newTestApp(t, `
+menu:
+  navigation:
+    "*": main
+    "*.1": list
+    "*.1.*": main
+`, map[string]menu.Menu{
+		"main": &prompt{name: "Main", options: []string{"List"}},
+		"list": &prompt{name: "List", options: []string{"a", "b", "c", "d", "e", "f"}, perPage: 3},
+	})
+
+	send(t, app, "s1", "*123#")
+	if s := send(t, app, "s1", "1"); !strings.Contains(s.Message, "More") {
+		t.Fatalf("message = %q, want a paginated list", s.Message)
+	}
+
+	c := *f.config
+	c.Menu.Navigation = map[string]string{"*": "main"}
+	f.activate(f.build(&c))
+
+	s := send(t, app, "s1", "1")
+	if s.Message != "Invalid menu option" || s.Stage != "COMPLETE" {
+		t.Errorf("screen = %+v, want the session ended as an invalid selection", s)
+	}
+}
+
+func TestSecretInput(t *testing.T) {
+
+	f, app := newTestApp(t, `
+menu:
+  navigation:
+    "*": main
+    "*.1": pin
+    "*.1.*": balance
+    "*.1.*.1": history
+`, map[string]menu.Menu{
+		"main":    &prompt{name: "Main", options: []string{"Balance"}},
+		"pin":     menu.PinInput("Enter your PIN", "pin", 4),
+		"balance": &prompt{name: "Balance", options: []string{"History"}},
+		"history": &prompt{name: "History"},
+	})
+
+	sink := journey.NewInMemory()
+	f.journey = journey.NewRecorder(sink, []byte("secret"))
+
+	for _, msg := range []string{"*123#", "1", "4321", "1"} {
+		send(t, app, "s1", msg)
+	}
+	f.journey.Close()
+
+	ss, _ := f.GetSession("s1")
+	if want := []string{"*123#", "1", "*", "1"}; !reflect.DeepEqual(ss.Selections, want) {
+		t.Errorf("selections = %v, want %v", ss.Selections, want)
+	}
+	for _, fr := range ss.Stack {
+		for _, sel := range fr.Selections {
+			if sel == "4321" {
+				t.Errorf("stack frame %v holds the PIN", fr.Selections)
+			}
+		}
+	}
+	if ss.Attributes["pin"] != "4321" || !ss.Secrets["pin"] {
+		t.Errorf("pin attribute = %q, secret %v", ss.Attributes["pin"], ss.Secrets["pin"])
+	}
+
+	for _, h := range sink.Hops("s1") {
+		if h.Message == "4321" {
+			t.Errorf("journey recorded the PIN on route %s", h.InputRoute)
+		}
+	}
+}
+
+func TestInputNotTemplated(t *testing.T) {
+
+	_, app := newTestApp(t, `
+menu:
+  navigation:
+    "*": main
+    "*.*": echo
+`, map[string]menu.Menu{
+		"main": &prompt{name: "Main"},
+		"echo": &echo{},
+	})
+
+	send(t, app, "s1", "*123#")
+	if s := send(t, app, "s1", "{{.Msisdn}}"); s.Message != "You entered {{.Msisdn}}" {
+		t.Errorf("message = %q, want the input as sent", s.Message)
+	}
+}
+
+func TestPagingKeys(t *testing.T) {
+
+	_, app := newTestApp(t, `
+menu:
+  navigation:
+    "*": list
+    "*.*": main
+`, map[string]menu.Menu{
+		"main": &prompt{name: "Main"},
+		"list": &prompt{name: "List", options: []string{"a", "b", "c", "d", "e", "f"}, perPage: 3},
+	})
+
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{msg: "*123#", want: "List\n1. a\n2. b\n3. c\n0. More"},
+		{msg: "0", want: "Please select an option:\n1. d\n2. e\n3. f\n#. Previous"},
+		{msg: "#", want: "List\n1. a\n2. b\n3. c\n0. More"},
+	}
+
+	for _, tt := range tests {
+		if s := send(t, app, "s1", tt.msg); s.Message != tt.want {
+			t.Errorf("%q: message = %q, want %q", tt.msg, s.Message, tt.want)
+		}
+	}
+}
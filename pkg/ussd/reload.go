@@ -0,0 +1,172 @@
+package ussd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/jamesdube/ussd/internal/utils"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+const reloadDelay = 500 * time.Millisecond
+
+// configureMenus builds the route table from config.yaml and makes it
+// active. Problems are logged, or fail the startup in strict mode. Keys
+// overlapping always fail it.
+func (f *Framework) configureMenus() {
+
+	if err := f.checkKeys(); err != nil {
+		panic(fmt.Errorf("fatal error in menu keys: %w", err))
+	}
+
+	t := f.build(f.config)
+	t.checksum = checksum(f.config)
+
+	err := t.validate()
+	if err != nil {
+		if f.config.Menu.Strict {
+			panic(err)
+		}
+		for _, p := range err.(*ValidationError).Problems {
+			utils.Logger.Warn(p)
+		}
+	}
+
+	f.activate(t)
+}
+
+// reload reads config.yaml and the menu definition files again and swaps
+// the route table when it changed. Tables with routes left out are rejected,
+// as are those failing validation in strict mode, so the active one keeps
+// serving. Sessions keep their selections and continue on the new table.
+func (f *Framework) reload() {
+
+	b, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		f.rejectReload(err)
+		return
+	}
+
+	var c config
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		f.rejectReload(err)
+		return
+	}
+
+	sum := checksum(&c)
+	if sum == f.current().checksum {
+		return
+	}
+
+	t := f.build(&c)
+	t.checksum = sum
+
+	err = t.validate()
+	if err != nil {
+		if len(t.errors) > 0 || f.config.Menu.Strict {
+			f.rejectReload(err)
+			return
+		}
+		for _, p := range err.(*ValidationError).Problems {
+			utils.Logger.Warn(p)
+		}
+	}
+
+	f.activate(t)
+	configReloads.WithLabelValues("success").Inc()
+	utils.Logger.Info("reloaded menu configuration", "checksum", sum)
+}
+
+func (f *Framework) rejectReload(err error) {
+	configReloads.WithLabelValues("failure").Inc()
+	utils.Logger.Error("menu configuration not reloaded", "error", err)
+}
+
+func (f *Framework) activate(t *tree) {
+	f.tree.Store(t)
+	configVersion.Reset()
+	configVersion.WithLabelValues(t.checksum).Set(float64(time.Now().Unix()))
+}
+
+// watch reloads the menu configuration whenever config.yaml or one of the
+// menu definition files changes. Directories are watched rather than files
+// so editors replacing the file are noticed.
+func (f *Framework) watch() error {
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	watched := map[string]bool{}
+	files := map[string]bool{}
+	track := func() {
+		for _, p := range append([]string{configFile}, f.current().config.Menu.Files...) {
+			p = filepath.Clean(p)
+			files[p] = true
+			dir := filepath.Dir(p)
+			if watched[dir] {
+				continue
+			}
+			err := w.Add(dir)
+			if err != nil {
+				utils.Logger.Warn("could not watch menu configuration", "dir", dir, "error", err)
+				continue
+			}
+			watched[dir] = true
+		}
+	}
+	track()
+
+	go func() {
+		var timer *time.Timer
+		reload := make(chan struct{}, 1)
+
+		for {
+			select {
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if !files[filepath.Clean(e.Name)] || e.Op == fsnotify.Chmod {
+					continue
+				}
+				// editors write in several steps, wait for them to settle
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, func() {
+					reload <- struct{}{}
+				})
+			case <-reload:
+				f.reload()
+				track()
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				utils.Logger.Error("menu configuration watcher failed", "error", err)
+			}
+		}
+	}()
+
+	utils.Logger.Info("watching menu configuration for changes")
+	return nil
+}
+
+// checksum identifies a menu configuration by the content of config.yaml
+// and of the menu definition files.
+func checksum(c *config) string {
+
+	h := sha256.New()
+	for _, p := range append([]string{configFile}, c.Menu.Files...) {
+		b, _ := ioutil.ReadFile(p)
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
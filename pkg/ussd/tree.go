@@ -0,0 +1,173 @@
+package ussd
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"github.com/jamesdube/ussd/pkg/middleware"
+	"github.com/jamesdube/ussd/pkg/router"
+	"sort"
+)
+
+// tree is the route table and the menus built from the navigation config,
+// it is replaced as a whole when the configuration is reloaded.
+type tree struct {
+	router   *router.Router
+	menus    *menu.Registry
+	config   *config
+	errors   []error
+	checksum string
+}
+
+// mount is a sub-router added under a prefix route.
+type mount struct {
+	prefix string
+	router *router.Router
+}
+
+// guardEntry is a guard attached to a route pattern through Ussd.Guard.
+type guardEntry struct {
+	pattern string
+	guard   middleware.Guard
+}
+
+func (f *Framework) current() *tree {
+	t, _ := f.tree.Load().(*tree)
+	return t
+}
+
+// routes returns the active router.
+func (f *Framework) routes() *router.Router {
+	return f.current().router
+}
+
+const treeKey = "ussd.tree"
+
+// pin fixes the tree used for the rest of the request, so a reload while it
+// is handled can not mix two route tables.
+func (f *Framework) pin(ctx *fiber.Ctx) {
+	ctx.Locals(treeKey, f.current())
+}
+
+// table returns the router pinned for the request, the active one when none
+// is.
+func (f *Framework) table(ctx *fiber.Ctx) *router.Router {
+	if t, ok := ctx.Locals(treeKey).(*tree); ok {
+		return t.router
+	}
+	return f.routes()
+}
+
+// build creates the route table described by c from the menus added in code,
+// the declarative menus of c, the mounted routers and the guards. Problems
+// that leave routes out are recorded in the tree errors.
+func (f *Framework) build(c *config) *tree {
+
+	t := &tree{
+		router: router.NewRouter(),
+		menus:  menu.NewRegistry(),
+		config: c,
+	}
+
+	for _, n := range f.menuRegistry.Names() {
+		t.menus.Add(n, f.menuRegistry.Find(n))
+	}
+	t.loadDefinitions()
+
+	keys := make([]string, 0, len(c.Menu.Navigation))
+	for k := range c.Menu.Navigation {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		t.addRoute(k, c.Menu.Navigation[k])
+	}
+
+	for _, m := range f.mounts {
+		err := t.router.Mount(m.prefix, m.router)
+		if err != nil {
+			utils.Logger.Error("could not mount router", "prefix", m.prefix, "error", err)
+			t.errors = append(t.errors, err)
+			continue
+		}
+		utils.Logger.Debug("mounted router", "prefix", m.prefix)
+	}
+
+	for _, g := range f.guards {
+		err := t.router.Guard(g.pattern, g.guard)
+		if err != nil {
+			t.errors = append(t.errors, err)
+		}
+	}
+
+	for _, rt := range t.router.Routes() {
+		if e, ok := rt.Menu.(*router.Experiment); ok && e.Observe == nil {
+			e.Observe = observeExperiment
+		}
+	}
+
+	return t
+}
+
+func (t *tree) addRoute(k string, m string) {
+
+	mn := t.menus.Find(m)
+	if mn == nil {
+		utils.Logger.Warn("menu not registered, skipping route", "routeKey", k, "routeMenu", m)
+		t.errors = append(t.errors, fmt.Errorf("route %s uses unregistered menu %s", k, m))
+		return
+	}
+
+	err := t.router.AddRoute(k, m, mn)
+	if err != nil {
+		utils.Logger.Error("could not register route", "routeKey", k, "routeMenu", m, "error", err)
+		t.errors = append(t.errors, err)
+		return
+	}
+	utils.Logger.Debug("registered route", "routeKey", k, "routeMenu", m)
+}
+
+// loadDefinitions registers the declarative menus of config.yaml and of the
+// files it lists, later files win over earlier ones and over config.yaml.
+// Menus added in code take precedence over definitions of the same name.
+func (t *tree) loadDefinitions() {
+
+	defs := map[string]*menu.Definition{}
+	for k, d := range t.config.Menu.Definitions {
+		defs[k] = d
+	}
+
+	for _, p := range t.config.Menu.Files {
+		fd, err := menu.LoadDefinitions(p)
+		if err != nil {
+			utils.Logger.Error("could not load menu definitions", "file", p, "error", err)
+			t.errors = append(t.errors, err)
+			continue
+		}
+		for k, d := range fd {
+			defs[k] = d
+		}
+	}
+
+	for name, d := range defs {
+		if t.menus.Find(name) != nil {
+			utils.Logger.Warn("menu definition shadowed by a menu added in code", "menu", name)
+			continue
+		}
+		err := d.Compile()
+		if err != nil {
+			utils.Logger.Error("could not compile menu definition", "menu", name, "error", err)
+			t.errors = append(t.errors, fmt.Errorf("menu %s: %w", name, err))
+			continue
+		}
+		t.menus.Add(name, d)
+		utils.Logger.Debug("loaded menu definition", "menu", name)
+	}
+}
+
+func observeExperiment(experiment string, variant string) {
+	utils.Logger.Debug("assigned experiment variant", "experiment", experiment, "variant", variant)
+	experimentAssignments.WithLabelValues(experiment, variant).Inc()
+}
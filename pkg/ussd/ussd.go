@@ -5,8 +5,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/jamesdube/ussd/internal/utils"
+	"github.com/jamesdube/ussd/pkg/i18n"
+	"github.com/jamesdube/ussd/pkg/journey"
 	"github.com/jamesdube/ussd/pkg/menu"
 	"github.com/jamesdube/ussd/pkg/middleware"
+	"github.com/jamesdube/ussd/pkg/router"
+	"io"
 	"log/slog"
 )
 
@@ -44,6 +48,69 @@ func (u *Ussd) AddMiddleware(m middleware.Middleware) {
 	u.framework.middlewareRegistry.Add(m)
 }
 
+// Mount adds the routes of r under the prefix route key, so a product area
+// can keep its own menus and middleware. The routes are added after those of
+// menu.navigation.
+func (u *Ussd) Mount(prefix string, r *router.Router) {
+	u.framework.mounts = append(u.framework.mounts, mount{prefix: prefix, router: r})
+}
+
+// Guard attaches g to the route pattern and the routes below it, it runs
+// after the request is routed and can block it, redirect it to another menu or
+// add session attributes.
+func (u *Ussd) Guard(pattern string, g middleware.Guard) error {
+	err := u.framework.routes().Guard(pattern, g)
+	if err != nil {
+		return err
+	}
+	u.framework.guards = append(u.framework.guards, guardEntry{pattern: pattern, guard: g})
+	return nil
+}
+
+// SetMessages replaces the message catalogs loaded from i18n.dir.
+func (u *Ussd) SetMessages(b *i18n.Bundle) {
+	u.framework.messages = b
+}
+
+// SetLanguageResolver replaces how the locale of a subscriber is found, by
+// default from the language session attribute.
+func (u *Ussd) SetLanguageResolver(r i18n.Resolver) {
+	u.framework.language = r
+}
+
+// OnAbandoned registers a handler called when a session expires in the
+// session store without being ended.
+func (u *Ussd) OnAbandoned(h AbandonedHandler) {
+	u.framework.abandonedHandlers = append(u.framework.abandonedHandlers, h)
+}
+
+// SetJourney records every hop of every session with the recorder.
+func (u *Ussd) SetJourney(r *journey.Recorder) {
+	u.framework.journey = r
+}
+
+// Validate configures the menus from config.yaml and checks the resulting
+// menu graph and keys, it is meant for tests and CI.
+func (u *Ussd) Validate() error {
+	if err := u.framework.checkKeys(); err != nil {
+		return err
+	}
+	return u.framework.build(u.framework.config).validate()
+}
+
+// Graph configures the menus from config.yaml and writes the navigation as a
+// "dot" or "mermaid" diagram, traffic holds optional counts by route key.
+func (u *Ussd) Graph(w io.Writer, format string, traffic map[string]float64) error {
+	r := u.framework.build(u.framework.config).router
+	switch format {
+	case "dot":
+		return r.Dot(w, traffic)
+	case "mermaid":
+		return r.Mermaid(w, traffic)
+	}
+	return fmt.Errorf("unknown graph format %s", format)
+}
+
 func (u *Ussd) Start() {
 
 	app := fiber.New(fiber.Config{
@@ -53,9 +120,17 @@ func (u *Ussd) Start() {
 
 	u.framework.configureMenus()
 
+	if u.framework.config.Menu.Watch {
+		err := u.framework.watch()
+		if err != nil {
+			utils.Logger.Error("could not watch menu configuration", "error", err)
+		}
+	}
+
 	app.Use(recover.New())
 
 	SetupRoutes(u.framework, app)
+	SetupAdmin(u.framework, app)
 	SetupMetrics(app)
 	//utils.SetLogger(u.logger)
 
@@ -0,0 +1,56 @@
+package ussd
+
+import (
+	"fmt"
+	"github.com/jamesdube/ussd/pkg/menu"
+	"strings"
+)
+
+// ValidationError lists the problems found in the menu graph.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid menu graph: " + strings.Join(e.Problems, "; ")
+}
+
+// validate reports the routes that could not be registered, routes whose
+// parent menu is never routed, menus that are registered but never routed and
+// menu definitions moving to menus that are not routed.
+func (t *tree) validate() error {
+
+	var problems []string
+
+	for _, err := range t.errors {
+		problems = append(problems, err.Error())
+	}
+
+	routed := map[string]bool{}
+	for _, rt := range t.router.Routes() {
+		routed[rt.Name] = true
+		if !t.router.Reachable(rt) {
+			problems = append(problems, fmt.Sprintf("route %s to menu %s is unreachable, no menu is routed before it", rt.Key, rt.Name))
+		}
+	}
+
+	for _, n := range t.menus.Names() {
+		if !routed[n] {
+			problems = append(problems, fmt.Sprintf("menu %s is registered but never routed", n))
+		}
+
+		if d, ok := t.menus.Find(n).(*menu.Definition); ok {
+			for _, target := range d.Targets() {
+				if !routed[target] {
+					problems = append(problems, fmt.Sprintf("menu %s moves to %s which is not routed", n, target))
+				}
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: problems}
+}